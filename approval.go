@@ -0,0 +1,161 @@
+package openairt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/codewandler/openairt-go/events"
+	"github.com/codewandler/openairt-go/tool"
+	"log/slog"
+	"time"
+)
+
+// errConfirmationExpired ends the span of a call the user never confirmed.
+var errConfirmationExpired = errors.New("confirmation expired")
+
+// pendingCall is a call waiting for the user to confirm it.
+type pendingCall struct {
+	call  tool.Call
+	asked int64 // user items when the user was asked, see Client.userItems
+	timer *time.Timer
+}
+
+// confirmTool is added to the session when a tool approver is configured.
+// The model calls it once the user answered a confirmation question.
+var confirmTool = tool.Tool{
+	Type:        "function",
+	Name:        "confirm_tool_call",
+	Description: "Report the answer of the user after you asked them to confirm an action. Only call this after the user clearly confirmed or declined.",
+	Parameters: tool.Parameters{
+		Type: "object",
+		Properties: tool.Properties{
+			"confirmation_id": {
+				Type:        "string",
+				Description: "The confirmation_id of the action the user was asked about.",
+			},
+			"confirmed": {
+				Type:        "boolean",
+				Description: "Whether the user confirmed the action.",
+			},
+		},
+		Required: []string{"confirmation_id", "confirmed"},
+	},
+}
+
+// approveToolCall asks the approver whether call may run.
+func (c *Client) approveToolCall(call tool.Call) (output string, instruction string) {
+	decision, err := c.config.approver(c.ctx, call)
+	if err != nil {
+		err = fmt.Errorf("approval failed: %w", err)
+		c.telemetry.toolError(call.ID, err)
+		c.telemetry.endTool(call.ID)
+		return c.toolOutput(call, nil, err), ""
+	}
+
+	c.log().Debug("tool call approval", slog.String("call_id", call.ID), slog.String("name", call.Name), slog.String("verdict", string(decision.Verdict)))
+
+	switch decision.Verdict {
+	case tool.VerdictApprove:
+		return c.callTool(call), ""
+	case tool.VerdictConfirm:
+		c.pendingMu.Lock()
+		c.pending[call.ID] = &pendingCall{
+			call:  call,
+			asked: c.userItems,
+			timer: time.AfterFunc(c.config.confirmTimeout, func() {
+				c.expireConfirmation(call.ID)
+			}),
+		}
+		c.pendingMu.Unlock()
+
+		output := c.toolOutput(call, map[string]any{
+			"status":          "confirmation_required",
+			"confirmation_id": call.ID,
+			"prompt":          decision.Prompt,
+		}, nil)
+
+		return output, fmt.Sprintf(
+			"Before %s can run, ask the user to confirm: %q. Once the user answered, call %s with confirmation_id %q and whether they confirmed.",
+			call.Name, decision.Prompt, confirmTool.Name, call.ID,
+		)
+	default:
+		c.telemetry.endTool(call.ID)
+
+		reason := decision.Reason
+		if reason == "" {
+			reason = "the call was not approved"
+		}
+
+		return c.toolOutput(call, map[string]any{
+			"status": "denied",
+			"reason": reason,
+		}, nil), ""
+	}
+}
+
+// handleConfirmation runs or drops a call that was waiting for the user. A
+// call is only confirmed if the user said something since they were asked,
+// the model must not answer for them.
+func (c *Client) handleConfirmation(o events.ResponseDoneOutput) string {
+	confirmation := tool.Call{ID: o.CallID, Name: o.Name}
+
+	var args struct {
+		ConfirmationID string `json:"confirmation_id"`
+		Confirmed      bool   `json:"confirmed"`
+	}
+	if err := json.Unmarshal([]byte(o.Arguments), &args); err != nil {
+		return c.toolOutput(confirmation, nil, fmt.Errorf("invalid arguments: %w", err))
+	}
+
+	c.pendingMu.Lock()
+	p, ok := c.pending[args.ConfirmationID]
+	answered := ok && c.userItems > p.asked
+	if ok && (answered || !args.Confirmed) {
+		p.timer.Stop()
+		delete(c.pending, args.ConfirmationID)
+	}
+	c.pendingMu.Unlock()
+
+	if !ok {
+		return c.toolOutput(confirmation, nil, fmt.Errorf("unknown or expired confirmation_id: %s", args.ConfirmationID))
+	}
+	if args.Confirmed && !answered {
+		c.log().Warn("tool call confirmed without an answer of the user", slog.String("call_id", p.call.ID), slog.String("name", p.call.Name))
+		return c.toolOutput(confirmation, nil, fmt.Errorf("the user did not answer yet, ask them to confirm and wait for their answer"))
+	}
+	call := p.call
+
+	if !args.Confirmed {
+		c.telemetry.endTool(call.ID)
+
+		return c.toolOutput(confirmation, map[string]any{
+			"status": "cancelled",
+			"reason": "the user did not confirm",
+		}, nil)
+	}
+
+	return c.callTool(call)
+}
+
+// userItem notes a message of the user, the answer to the confirmations
+// asked so far.
+func (c *Client) userItem() {
+	c.pendingMu.Lock()
+	c.userItems++
+	c.pendingMu.Unlock()
+}
+
+// expireConfirmation drops a call the user did not confirm in time.
+func (c *Client) expireConfirmation(callID string) {
+	c.pendingMu.Lock()
+	_, ok := c.pending[callID]
+	delete(c.pending, callID)
+	c.pendingMu.Unlock()
+
+	if !ok {
+		return
+	}
+	c.log().Debug("tool call confirmation expired", slog.String("call_id", callID))
+	c.telemetry.toolError(callID, errConfirmationExpired)
+	c.telemetry.endTool(callID)
+}
@@ -0,0 +1,239 @@
+package openairt
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/codewandler/openairt-go/events"
+	"github.com/codewandler/openairt-go/tool"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestToolApproverDeny(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	approved := make(chan tool.Call, 1)
+	client, srv := openTestClient(t, WithToolApprover(func(ctx context.Context, call tool.Call) (tool.Decision, error) {
+		approved <- call
+		return tool.Deny("transfers are disabled"), nil
+	}))
+	called := make(chan string, 1)
+	client.OnToolCall(func(name string, args map[string]any) (any, error) {
+		called <- name
+		return nil, nil
+	})
+
+	require.NoError(t, srv.Send(functionCallDone("transfer", "call_1", `{"amount": 100}`)))
+
+	evt, err := srv.Next(ctx, "conversation.item.create")
+	require.NoError(t, err)
+	var output events.ConversationItemCreateEvent
+	require.NoError(t, evt.Decode(&output))
+	require.JSONEq(t, `{"status": "denied", "reason": "transfers are disabled"}`, output.Item.Output)
+
+	_, err = srv.Next(ctx, "response.create")
+	require.NoError(t, err)
+
+	call := <-approved
+	require.Equal(t, "transfer", call.Name)
+	require.Equal(t, float64(100), call.Arguments["amount"])
+	require.Empty(t, called, "handler must not be called")
+}
+
+// userCommitted is the user message of the input audio buffer.
+func userCommitted(itemID string) map[string]any {
+	return map[string]any{
+		"type":     "input_audio_buffer.committed",
+		"event_id": "evt_" + itemID,
+		"item_id":  itemID,
+	}
+}
+
+func TestToolApproverConfirm(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, srv := openTestClient(t, WithTools(tool.Tool{Type: "function", Name: "transfer"}), WithToolApprover(func(ctx context.Context, call tool.Call) (tool.Decision, error) {
+		return tool.Confirm("Transfer 100 EUR to Bob?"), nil
+	}))
+
+	update, err := srv.Next(ctx, "session.update")
+	require.NoError(t, err)
+	var session events.SessionUpdateEvent
+	require.NoError(t, update.Decode(&session))
+	require.Len(t, session.Session.Tools, 2)
+	require.Equal(t, confirmTool.Name, session.Session.Tools[1].Name)
+
+	called := make(chan map[string]any, 1)
+	client.OnToolCall(func(name string, args map[string]any) (any, error) {
+		called <- args
+		return map[string]any{"ok": true}, nil
+	})
+
+	require.NoError(t, srv.Send(functionCallDone("transfer", "call_1", `{"amount": 100}`)))
+
+	evt, err := srv.Next(ctx, "conversation.item.create")
+	require.NoError(t, err)
+	var output events.ConversationItemCreateEvent
+	require.NoError(t, evt.Decode(&output))
+	require.JSONEq(t, `{"status": "confirmation_required", "confirmation_id": "call_1", "prompt": "Transfer 100 EUR to Bob?"}`, output.Item.Output)
+
+	// the confirmation is asked for in a system message, the session
+	// instructions are left alone
+	evt, err = srv.Next(ctx, "conversation.item.create")
+	require.NoError(t, err)
+	var message events.ConversationItemCreateEvent
+	require.NoError(t, evt.Decode(&message))
+	require.Equal(t, "system", message.Item.Role)
+	require.Contains(t, message.Item.Content[0].Text, "Transfer 100 EUR to Bob?")
+	require.Contains(t, message.Item.Content[0].Text, confirmTool.Name)
+
+	evt, err = srv.Next(ctx, "response.create")
+	require.NoError(t, err)
+	var create events.ResponseCreateEvent
+	require.NoError(t, evt.Decode(&create))
+	require.Empty(t, create.Response.Instructions)
+	require.Empty(t, called)
+
+	require.NoError(t, srv.Send(userCommitted("item_user")))
+	args, _ := json.Marshal(map[string]any{"confirmation_id": "call_1", "confirmed": true})
+	require.NoError(t, srv.Send(functionCallDone(confirmTool.Name, "call_2", string(args))))
+
+	select {
+	case args := <-called:
+		require.Equal(t, float64(100), args["amount"])
+	case <-ctx.Done():
+		t.Fatal("handler was not called")
+	}
+
+	evt, err = srv.Next(ctx, "conversation.item.create")
+	require.NoError(t, err)
+	require.NoError(t, evt.Decode(&output))
+	require.Equal(t, "call_2", output.Item.CallID)
+	require.JSONEq(t, `{"ok": true}`, output.Item.Output)
+}
+
+func TestToolApproverEnvelope(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	envelope := func(call tool.Call, result any, err error) any {
+		return map[string]any{"name": call.Name, "result": result}
+	}
+	client, srv := openTestClient(t,
+		WithToolEnvelope(envelope),
+		WithToolOutputLimit(100),
+		WithToolApprover(func(ctx context.Context, call tool.Call) (tool.Decision, error) {
+			return tool.Deny(strings.Repeat("transfers are disabled ", 10)), nil
+		}),
+	)
+	client.OnToolCall(func(name string, args map[string]any) (any, error) {
+		return nil, nil
+	})
+
+	require.NoError(t, srv.Send(functionCallDone("transfer", "call_1", `{"amount": 100}`)))
+
+	evt, err := srv.Next(ctx, "conversation.item.create")
+	require.NoError(t, err)
+	var output events.ConversationItemCreateEvent
+	require.NoError(t, evt.Decode(&output))
+	require.LessOrEqual(t, len(output.Item.Output), 100)
+
+	var truncated struct {
+		Truncated bool   `json:"truncated"`
+		Content   string `json:"content"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(output.Item.Output), &truncated))
+	require.True(t, truncated.Truncated)
+	require.True(t, strings.HasPrefix(truncated.Content, `{"name":"transfer"`), truncated.Content)
+}
+
+func TestToolApproverConfirmWithoutAnswer(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, srv := openTestClient(t, WithToolApprover(func(ctx context.Context, call tool.Call) (tool.Decision, error) {
+		return tool.Confirm("Transfer 100 EUR to Bob?"), nil
+	}))
+	called := make(chan map[string]any, 1)
+	client.OnToolCall(func(name string, args map[string]any) (any, error) {
+		called <- args
+		return map[string]any{"ok": true}, nil
+	})
+
+	require.NoError(t, srv.Send(functionCallDone("transfer", "call_1", `{"amount": 100}`)))
+	_, err := srv.Next(ctx, "response.create")
+	require.NoError(t, err)
+
+	// the model confirms in the next response, the user did not say a word
+	args, _ := json.Marshal(map[string]any{"confirmation_id": "call_1", "confirmed": true})
+	require.NoError(t, srv.Send(functionCallDone(confirmTool.Name, "call_2", string(args))))
+
+	evt, err := srv.Next(ctx, "conversation.item.create")
+	require.NoError(t, err)
+	var output events.ConversationItemCreateEvent
+	require.NoError(t, evt.Decode(&output))
+	require.Equal(t, "call_2", output.Item.CallID)
+	require.Contains(t, output.Item.Output, "the user did not answer yet")
+	_, err = srv.Next(ctx, "response.create")
+	require.NoError(t, err)
+	require.Empty(t, called)
+
+	// a typed answer counts like a spoken one
+	require.NoError(t, srv.Send(map[string]any{
+		"type":     "conversation.item.created",
+		"event_id": "evt_user",
+		"item": map[string]any{
+			"id":      "item_user",
+			"type":    "message",
+			"role":    "user",
+			"content": []map[string]any{{"type": "input_text", "text": "yes"}},
+		},
+	}))
+	require.NoError(t, srv.Send(functionCallDone(confirmTool.Name, "call_3", string(args))))
+
+	select {
+	case args := <-called:
+		require.Equal(t, float64(100), args["amount"])
+	case <-ctx.Done():
+		t.Fatal("handler was not called")
+	}
+}
+
+func TestToolApproverConfirmationExpired(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, srv := openTestClient(t,
+		WithToolConfirmationTimeout(50*time.Millisecond),
+		WithToolApprover(func(ctx context.Context, call tool.Call) (tool.Decision, error) {
+			return tool.Confirm("Transfer 100 EUR to Bob?"), nil
+		}),
+	)
+	client.OnToolCall(func(name string, args map[string]any) (any, error) {
+		return nil, nil
+	})
+
+	require.NoError(t, srv.Send(functionCallDone("transfer", "call_1", `{"amount": 100}`)))
+	_, err := srv.Next(ctx, "response.create")
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		client.pendingMu.Lock()
+		defer client.pendingMu.Unlock()
+		return len(client.pending) == 0
+	}, time.Second, time.Millisecond)
+
+	require.NoError(t, srv.Send(userCommitted("item_user")))
+	args, _ := json.Marshal(map[string]any{"confirmation_id": "call_1", "confirmed": true})
+	require.NoError(t, srv.Send(functionCallDone(confirmTool.Name, "call_2", string(args))))
+
+	evt, err := srv.Next(ctx, "conversation.item.create")
+	require.NoError(t, err)
+	var output events.ConversationItemCreateEvent
+	require.NoError(t, evt.Decode(&output))
+	require.Contains(t, output.Item.Output, "unknown or expired confirmation_id")
+}
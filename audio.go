@@ -0,0 +1,137 @@
+package openairt
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/codewandler/openairt-go/audio/vad"
+	"github.com/codewandler/openairt-go/events"
+	"github.com/codewandler/openairt-go/internal/websocket"
+	nanoid "github.com/matoous/go-nanoid/v2"
+	"log/slog"
+)
+
+// apiSampleRate is the sample rate of pcm16 audio exchanged with the API.
+const apiSampleRate = 24_000
+
+// writeQueue is the number of events queued on the connection. It is small,
+// so the input audio of a stalled connection backs up in the input buffer,
+// where the OverflowPolicy applies.
+const writeQueue = 16
+
+// pumpInput sends the audio written to Audio() to the input audio buffer of
+// the API in chunks of the duration set with WithInputChunk, e.g. 160 bytes
+// per 20ms for G.711 at 8 kHz. Audio short of a chunk is sent after waiting
+// for the rest for the duration of a chunk. The input is closed once the
+// connection ws ended, however it ended.
+func (c *Client) pumpInput(ws *websocket.Client, input uint64) {
+	rate, sampleSize := c.config.audioRate(c.config.inFormat)
+	buf := make([]byte, max(1, int(float64(rate)*c.config.inputChunk.Seconds()))*sampleSize)
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ws.Done():
+			c.closeInput(input, ws.Err())
+		case <-done:
+		}
+	}()
+
+	for {
+		n, err := c.audioToAgent.readChunk(buf, c.config.inputChunk)
+		if err != nil {
+			// the input was closed
+			return
+		}
+
+		data := buf[:n]
+		if c.inputResampler != nil {
+			_, _ = c.inputResampler.Write(data)
+			data = c.inputResampler.drain()
+		}
+
+		var evt vad.Event
+		if c.vad != nil {
+			data, evt = c.vad.process(buf[:n], data)
+		}
+
+		if len(data) > 0 {
+			if err := c.appendInput(ws, data); err != nil {
+				c.log().Error("failed to send input audio", slog.Any("err", err))
+				c.closeInput(input, err)
+				return
+			}
+		}
+
+		// the audio of the chunk is sent before the end of speech commits it
+		if evt != vad.None {
+			c.handleLocalSpeech(evt)
+		}
+	}
+}
+
+// closeInput fails the writes of the input of a connection that ended with
+// err, so they do not block on audio that is never sent.
+func (c *Client) closeInput(input uint64, err error) {
+	if !errors.Is(err, ErrClosed) {
+		err = fmt.Errorf("%w: %w", ErrClosed, err)
+	}
+	c.audioToAgent.closeWithError(input, err)
+}
+
+// appendInput sends audio to the input audio buffer.
+func (c *Client) appendInput(ws *websocket.Client, data []byte) error {
+	id, _ := nanoid.New()
+
+	evtData, err := json.Marshal(map[string]any{
+		"event_id": id,
+		"type":     "input_audio_buffer.append",
+		"audio":    base64.StdEncoding.EncodeToString(data),
+	})
+	if err != nil {
+		return err
+	}
+
+	return ws.WriteText(evtData)
+}
+
+// handleAudioDelta writes response audio to the buffer read via OutputAudio
+// or to its output stream.
+func (c *Client) handleAudioDelta(evt *events.ResponseAudioDeltaEvent) {
+	data, err := base64.StdEncoding.DecodeString(evt.Delta)
+	if err != nil {
+		c.log().Error("failed to decode base64 data", slog.String("response_id", evt.ResponseId), slog.String("item_id", evt.ItemID), slog.Any("err", err))
+		return
+	}
+	c.telemetry.audioDelta()
+	c.turns.audioDelta(evt.ResponseId)
+
+	if c.outputResampler != nil {
+		c.outputMu.Lock()
+		_, _ = c.outputResampler.Write(data)
+		data = c.outputResampler.drain()
+		c.outputMu.Unlock()
+	}
+
+	if c.playout != nil {
+		c.playout.write(evt.ItemID, data)
+		return
+	}
+
+	if c.streams != nil {
+		if c.streams.write(evt.ResponseId, evt.ItemID, data) && c.config.recorder != nil {
+			c.config.recorder.agentItem(evt.ItemID, len(data))
+		}
+		return
+	}
+
+	if c.config.recorder != nil {
+		c.config.recorder.agentItem(evt.ItemID, len(data))
+	}
+
+	if _, err = c.audioToUser.Write(data); err != nil {
+		c.log().Error("failed to write to audio read buffer", slog.String("response_id", evt.ResponseId), slog.String("item_id", evt.ItemID), slog.Any("err", err))
+	}
+}
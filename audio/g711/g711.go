@@ -0,0 +1,139 @@
+// Package g711 converts between PCM16 and G.711 µ-law / A-law audio.
+//
+// PCM16 audio is mono, signed 16 bit little endian. G.711 audio has one byte
+// per sample, telephony uses it at 8 kHz.
+package g711
+
+import "encoding/binary"
+
+const (
+	ulawBias = 0x84
+	ulawClip = 32635
+)
+
+// aLawSegmentEnds are the upper bounds of the A-law segments for 13 bit
+// samples.
+var aLawSegmentEnds = [8]int{0x1F, 0x3F, 0x7F, 0xFF, 0x1FF, 0x3FF, 0x7FF, 0xFFF}
+
+// EncodeULaw converts PCM16 to µ-law.
+func EncodeULaw(pcm []byte) []byte {
+	return encode(pcm, LinearToULaw)
+}
+
+// DecodeULaw converts µ-law to PCM16.
+func DecodeULaw(ulaw []byte) []byte {
+	return decode(ulaw, ULawToLinear)
+}
+
+// EncodeALaw converts PCM16 to A-law.
+func EncodeALaw(pcm []byte) []byte {
+	return encode(pcm, LinearToALaw)
+}
+
+// DecodeALaw converts A-law to PCM16.
+func DecodeALaw(alaw []byte) []byte {
+	return decode(alaw, ALawToLinear)
+}
+
+// LinearToULaw encodes a single sample as µ-law.
+func LinearToULaw(sample int16) byte {
+	v := int(sample)
+
+	var sign int
+	if v < 0 {
+		v = -v
+		sign = 0x80
+	}
+	if v > ulawClip {
+		v = ulawClip
+	}
+	v += ulawBias
+
+	exponent := 7
+	for mask := 0x4000; v&mask == 0 && exponent > 0; mask >>= 1 {
+		exponent--
+	}
+	mantissa := (v >> (exponent + 3)) & 0x0F
+
+	return ^byte(sign | exponent<<4 | mantissa)
+}
+
+// ULawToLinear decodes a single µ-law sample.
+func ULawToLinear(u byte) int16 {
+	u = ^u
+
+	exponent := int(u>>4) & 0x07
+	mantissa := int(u) & 0x0F
+	v := ((mantissa << 3) + ulawBias) << exponent
+	v -= ulawBias
+
+	if u&0x80 != 0 {
+		return int16(-v)
+	}
+	return int16(v)
+}
+
+// LinearToALaw encodes a single sample as A-law.
+func LinearToALaw(sample int16) byte {
+	v := int(sample) >> 3
+
+	var mask byte = 0xD5
+	if v < 0 {
+		mask = 0x55
+		v = -v - 1
+	}
+
+	segment := 0
+	for segment < len(aLawSegmentEnds) && v > aLawSegmentEnds[segment] {
+		segment++
+	}
+	if segment == len(aLawSegmentEnds) {
+		return 0x7F ^ mask
+	}
+
+	a := byte(segment << 4)
+	if segment < 2 {
+		a |= byte(v>>1) & 0x0F
+	} else {
+		a |= byte(v>>segment) & 0x0F
+	}
+
+	return a ^ mask
+}
+
+// ALawToLinear decodes a single A-law sample.
+func ALawToLinear(a byte) int16 {
+	a ^= 0x55
+
+	v := int(a&0x0F) << 4
+	switch segment := int(a&0x70) >> 4; segment {
+	case 0:
+		v += 8
+	case 1:
+		v += 0x108
+	default:
+		v += 0x108
+		v <<= segment - 1
+	}
+
+	if a&0x80 != 0 {
+		return int16(v)
+	}
+	return int16(-v)
+}
+
+func encode(pcm []byte, f func(int16) byte) []byte {
+	out := make([]byte, len(pcm)/2)
+	for i := range out {
+		out[i] = f(int16(binary.LittleEndian.Uint16(pcm[i*2:])))
+	}
+	return out
+}
+
+func decode(data []byte, f func(byte) int16) []byte {
+	out := make([]byte, len(data)*2)
+	for i, b := range data {
+		binary.LittleEndian.PutUint16(out[i*2:], uint16(f(b)))
+	}
+	return out
+}
@@ -0,0 +1,73 @@
+package g711
+
+import (
+	"encoding/binary"
+	"github.com/stretchr/testify/require"
+	"math"
+	"testing"
+)
+
+func TestSilence(t *testing.T) {
+	require.Equal(t, byte(0xFF), LinearToULaw(0))
+	require.Equal(t, byte(0xD5), LinearToALaw(0))
+	require.Equal(t, int16(0), ULawToLinear(0xFF))
+	require.Equal(t, int16(8), ALawToLinear(0xD5))
+}
+
+func TestCodeWordsAreStable(t *testing.T) {
+	for i := 0; i < 256; i++ {
+		b := byte(i)
+		require.Equal(t, b, LinearToALaw(ALawToLinear(b)), "a-law %#x", b)
+
+		// 0x7F is µ-law's negative zero
+		if b != 0x7F {
+			require.Equal(t, b, LinearToULaw(ULawToLinear(b)), "µ-law %#x", b)
+		}
+	}
+}
+
+func TestQuantizationError(t *testing.T) {
+	for s := math.MinInt16; s <= math.MaxInt16; s++ {
+		// the step size grows with the amplitude, 1/16 of it bounds the error
+		limit := math.Abs(float64(s))/16 + 16
+
+		u := ULawToLinear(LinearToULaw(int16(s)))
+		require.LessOrEqual(t, math.Abs(float64(u)-float64(s)), limit+float64(ulawBias), "µ-law %d", s)
+
+		a := ALawToLinear(LinearToALaw(int16(s)))
+		require.LessOrEqual(t, math.Abs(float64(a)-float64(s)), limit, "a-law %d", s)
+	}
+}
+
+func TestBuffers(t *testing.T) {
+	pcm := make([]byte, 8000*2)
+	for i := 0; i < 8000; i++ {
+		v := int16(10000 * math.Sin(2*math.Pi*440*float64(i)/8000))
+		binary.LittleEndian.PutUint16(pcm[i*2:], uint16(v))
+	}
+
+	for name, codec := range map[string]struct {
+		encode, decode func([]byte) []byte
+	}{
+		"ulaw": {EncodeULaw, DecodeULaw},
+		"alaw": {EncodeALaw, DecodeALaw},
+	} {
+		t.Run(name, func(t *testing.T) {
+			encoded := codec.encode(pcm)
+			require.Len(t, encoded, 8000)
+
+			decoded := codec.decode(encoded)
+			require.Len(t, decoded, len(pcm))
+
+			// G.711 keeps a signal to noise ratio of well above 30 dB
+			var signal, noise float64
+			for i := 0; i < 8000; i++ {
+				want := float64(int16(binary.LittleEndian.Uint16(pcm[i*2:])))
+				got := float64(int16(binary.LittleEndian.Uint16(decoded[i*2:])))
+				signal += want * want
+				noise += (got - want) * (got - want)
+			}
+			require.Greater(t, 10*math.Log10(signal/noise), 30.0)
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package opus
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Adapter converts between Opus packets and the PCM16 audio of Client.Audio.
+//
+// The rate passed to NewAdapter has to match the one of the client (see
+// openairt.WithSampleRate) and of the Encoder and Decoder. Opus runs at 48 kHz
+// internally, but NewEncoder and NewDecoder work at any of the rates below
+// directly, so the default 24 kHz of the client needs no resampling.
+type Adapter struct {
+	audio   io.ReadWriter
+	rate    int
+	enc     Encoder
+	dec     Decoder
+	decoded []int16
+	frame   []byte
+	samples []int16
+	packet  []byte
+}
+
+// NewAdapter creates an Adapter for audio at rate, one of 8, 12, 16, 24 or
+// 48 kHz. enc or dec may be nil if only one direction is used.
+func NewAdapter(audio io.ReadWriter, rate int, enc Encoder, dec Decoder) (*Adapter, error) {
+	switch rate {
+	case 8_000, 12_000, 16_000, 24_000, 48_000:
+	default:
+		return nil, fmt.Errorf("unsupported opus sample rate: %d", rate)
+	}
+
+	frameSize := rate * int(FrameDuration.Milliseconds()) / 1000
+
+	return &Adapter{
+		audio:   audio,
+		rate:    rate,
+		enc:     enc,
+		dec:     dec,
+		decoded: make([]int16, rate*120/1000),
+		frame:   make([]byte, frameSize*2),
+		samples: make([]int16, frameSize),
+		packet:  make([]byte, MaxPacketSize),
+	}, nil
+}
+
+// WritePacket decodes an Opus packet and writes the audio to the client.
+func (a *Adapter) WritePacket(packet []byte) error {
+	_, err := a.write(packet, 0)
+	return err
+}
+
+// write decodes a packet and writes the audio after the first skip samples.
+// It returns the number of decoded samples.
+func (a *Adapter) write(packet []byte, skip int) (int, error) {
+	if a.dec == nil {
+		return 0, errors.New("no opus decoder")
+	}
+
+	n, err := a.dec.Decode(packet, a.decoded)
+	if err != nil {
+		return 0, fmt.Errorf("decode opus: %w", err)
+	}
+	if skip >= n {
+		return n, nil
+	}
+
+	pcm := make([]byte, (n-skip)*2)
+	for i, s := range a.decoded[skip:n] {
+		binary.LittleEndian.PutUint16(pcm[i*2:], uint16(s))
+	}
+
+	_, err = a.audio.Write(pcm)
+	return n, err
+}
+
+// WriteRTP decodes the Opus packet carried by an RTP packet.
+func (a *Adapter) WriteRTP(packet []byte) error {
+	payload, err := RTPPayload(packet)
+	if err != nil {
+		return err
+	}
+	return a.WritePacket(payload)
+}
+
+// WriteOgg decodes an Ogg Opus stream until its end, skipping the pre-skip
+// samples of the stream.
+func (a *Adapter) WriteOgg(r io.Reader) error {
+	o, err := NewOggReader(r)
+	if err != nil {
+		return err
+	}
+	if o.Head().Channels != 1 && o.Head().Channels != 2 {
+		return fmt.Errorf("unsupported channel count: %d", o.Head().Channels)
+	}
+
+	skip := o.Head().PreSkip * a.rate / SampleRate
+
+	for {
+		packet, err := o.ReadPacket()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		n, err := a.write(packet, skip)
+		if err != nil {
+			return err
+		}
+		skip -= min(skip, n)
+	}
+}
+
+// ReadPacket reads 20 ms of audio from the client and returns it as an Opus
+// packet. It blocks until a full frame is available. The returned slice is
+// only valid until the next call.
+func (a *Adapter) ReadPacket() ([]byte, error) {
+	if a.enc == nil {
+		return nil, errors.New("no opus encoder")
+	}
+
+	if _, err := io.ReadFull(a.audio, a.frame); err != nil {
+		return nil, err
+	}
+	for i := range a.samples {
+		a.samples[i] = int16(binary.LittleEndian.Uint16(a.frame[i*2:]))
+	}
+
+	n, err := a.enc.Encode(a.samples, a.packet)
+	if err != nil {
+		return nil, fmt.Errorf("encode opus: %w", err)
+	}
+
+	return a.packet[:n], nil
+}
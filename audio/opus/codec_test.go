@@ -0,0 +1,63 @@
+package opus
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/stretchr/testify/require"
+	"math"
+	"os"
+	"testing"
+)
+
+// testdata/speech.opus is a recording of speech encoded at 8 kbit/s by
+// opusenc, taken with testdata/speech.pcm from the tests of
+// gopkg.in/hraban/opus.v2 (MIT). speech.pcm holds the first two seconds of
+// its decoding by libopus, mono PCM16 at 48 kHz.
+
+// snr returns the signal to noise ratio of got against want in dB.
+func snr(t *testing.T, want, got []byte) float64 {
+	t.Helper()
+	require.GreaterOrEqual(t, len(got), len(want))
+
+	var signal, noise float64
+	for i := 0; i < len(want); i += 2 {
+		w := float64(int16(binary.LittleEndian.Uint16(want[i:])))
+		g := float64(int16(binary.LittleEndian.Uint16(got[i:])))
+		signal += w * w
+		noise += (w - g) * (w - g)
+	}
+	return 10 * math.Log10(signal/noise)
+}
+
+func decodeSpeech(t *testing.T, rate int) []byte {
+	t.Helper()
+
+	dec, err := NewDecoder(rate)
+	require.NoError(t, err)
+	var audio bytes.Buffer
+	a, err := NewAdapter(&audio, rate, nil, dec)
+	require.NoError(t, err)
+
+	f, err := os.Open("testdata/speech.opus")
+	require.NoError(t, err)
+	defer f.Close()
+	require.NoError(t, a.WriteOgg(f))
+
+	return audio.Bytes()
+}
+
+func TestDecoder(t *testing.T) {
+	_, err := NewDecoder(44_100)
+	require.Error(t, err)
+
+	want, err := os.ReadFile("testdata/speech.pcm")
+	require.NoError(t, err)
+
+	// 10.8s of speech, matching libopus without the pre-skip
+	got := decodeSpeech(t, 48_000)
+	require.InDelta(t, 10.8, float64(len(got)/2)/48_000, 0.05)
+	require.Greater(t, snr(t, want, got), 40.0)
+
+	got = decodeSpeech(t, 24_000)
+	require.InDelta(t, 10.8, float64(len(got)/2)/24_000, 0.05)
+}
@@ -0,0 +1,26 @@
+package opus
+
+import (
+	"fmt"
+	pion "github.com/pion/opus"
+)
+
+// decoder decodes SILK, CELT and hybrid packets in pure Go.
+type decoder struct {
+	dec pion.Decoder
+}
+
+// NewDecoder creates a Decoder for mono audio at rate, one of 8, 12, 16, 24
+// or 48 kHz. Stereo packets are mixed down. The Decoder keeps state between
+// packets, so one is needed per stream.
+func NewDecoder(rate int) (Decoder, error) {
+	dec, err := pion.NewDecoderWithOutput(rate, 1)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported opus sample rate: %d", rate)
+	}
+	return &decoder{dec: dec}, nil
+}
+
+func (d *decoder) Decode(data []byte, pcm []int16) (int, error) {
+	return d.dec.DecodeToInt16(data, pcm)
+}
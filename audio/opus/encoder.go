@@ -0,0 +1,55 @@
+//go:build libopus
+
+package opus
+
+/*
+#cgo pkg-config: opus
+#include <opus.h>
+
+static int encoder_init(OpusEncoder *enc, opus_int32 rate) {
+	return opus_encoder_init(enc, rate, 1, OPUS_APPLICATION_VOIP);
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// encoder encodes with libopus. Its state is allocated by Go and holds no
+// pointers, so it needs no freeing.
+type encoder struct {
+	state []byte
+}
+
+// NewEncoder creates an Encoder for mono audio at rate, one of 8, 12, 16, 24
+// or 48 kHz, tuned for speech. It requires cgo, libopus and the libopus build
+// tag. The streams it encodes have a pre-skip of DefaultPreSkip.
+func NewEncoder(rate int) (Encoder, error) {
+	e := &encoder{state: make([]byte, C.opus_encoder_get_size(1))}
+	if code := C.encoder_init(e.ptr(), C.opus_int32(rate)); code != C.OPUS_OK {
+		return nil, fmt.Errorf("create opus encoder: %s", C.GoString(C.opus_strerror(code)))
+	}
+	return e, nil
+}
+
+func (e *encoder) ptr() *C.OpusEncoder {
+	return (*C.OpusEncoder)(unsafe.Pointer(&e.state[0]))
+}
+
+func (e *encoder) Encode(pcm []int16, data []byte) (int, error) {
+	if len(pcm) == 0 || len(data) == 0 {
+		return 0, ErrInvalidPacket
+	}
+
+	n := C.opus_encode(
+		e.ptr(),
+		(*C.opus_int16)(unsafe.Pointer(&pcm[0])), C.int(len(pcm)),
+		(*C.uchar)(unsafe.Pointer(&data[0])), C.opus_int32(len(data)),
+	)
+	if n < 0 {
+		return 0, fmt.Errorf("%s", C.GoString(C.opus_strerror(C.int(n))))
+	}
+	return int(n), nil
+}
@@ -0,0 +1,11 @@
+//go:build !libopus
+
+package opus
+
+import "errors"
+
+// NewEncoder creates an Encoder for mono audio at rate. Encoding needs libopus
+// through cgo: without the libopus build tag it always fails.
+func NewEncoder(rate int) (Encoder, error) {
+	return nil, errors.New("opus encoding requires the libopus build tag")
+}
@@ -0,0 +1,86 @@
+//go:build libopus
+
+package opus
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/stretchr/testify/require"
+	"io"
+	"math"
+	"testing"
+)
+
+func TestEncoder(t *testing.T) {
+	_, err := NewEncoder(44_100)
+	require.Error(t, err)
+
+	speech := decodeSpeech(t, 24_000)
+
+	enc, err := NewEncoder(24_000)
+	require.NoError(t, err)
+	a, err := NewAdapter(bytes.NewBuffer(speech), 24_000, enc, nil)
+	require.NoError(t, err)
+
+	var ogg bytes.Buffer
+	w, err := NewOggWriter(&ogg, 24_000, DefaultPreSkip)
+	require.NoError(t, err)
+	var packets int
+	for {
+		packet, err := a.ReadPacket()
+		if err == io.ErrUnexpectedEOF {
+			break
+		}
+		require.NoError(t, err)
+
+		n, err := PacketSamples(packet)
+		require.NoError(t, err)
+		require.Equal(t, 960, n)
+		require.NoError(t, w.WritePacket(packet))
+		packets++
+	}
+	require.NoError(t, w.Close())
+	require.Equal(t, len(speech)/2/480, packets)
+
+	// decoding the stream again gives the speech back, aligned by the pre-skip
+	dec, err := NewDecoder(24_000)
+	require.NoError(t, err)
+	var audio bytes.Buffer
+	a, err = NewAdapter(&audio, 24_000, nil, dec)
+	require.NoError(t, err)
+	require.NoError(t, a.WriteOgg(&ogg))
+
+	// lossy coding keeps the loudness of each frame, not the waveform
+	got := audio.Bytes()
+	require.Greater(t, correlation(envelope(speech[:len(got)]), envelope(got)), 0.99)
+}
+
+// envelope returns the RMS of each 20 ms frame of PCM16 at 24 kHz.
+func envelope(pcm []byte) []float64 {
+	var rms []float64
+	for ; len(pcm) >= 960; pcm = pcm[960:] {
+		var sum float64
+		for i := 0; i < 960; i += 2 {
+			s := float64(int16(binary.LittleEndian.Uint16(pcm[i:])))
+			sum += s * s
+		}
+		rms = append(rms, math.Sqrt(sum/480))
+	}
+	return rms
+}
+
+// correlation returns the Pearson correlation of a and b.
+func correlation(a, b []float64) float64 {
+	var meanA, meanB float64
+	for i := range a {
+		meanA += a[i] / float64(len(a))
+		meanB += b[i] / float64(len(b))
+	}
+	var cov, varA, varB float64
+	for i := range a {
+		cov += (a[i] - meanA) * (b[i] - meanB)
+		varA += (a[i] - meanA) * (a[i] - meanA)
+		varB += (b[i] - meanB) * (b[i] - meanB)
+	}
+	return cov / math.Sqrt(varA*varB)
+}
@@ -0,0 +1,264 @@
+package opus
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	oggHeaderSize = 27
+
+	oggContinued = 0x01
+	oggBOS       = 0x02
+	oggEOS       = 0x04
+)
+
+var (
+	oggCapture = []byte("OggS")
+	opusHead   = []byte("OpusHead")
+	opusTags   = []byte("OpusTags")
+	oggCRC     = oggCRCTable()
+)
+
+// Head is the identification header of an Ogg Opus stream (RFC 7845).
+type Head struct {
+	Channels int
+	// PreSkip is the number of samples at 48 kHz to discard at the start.
+	PreSkip int
+	// InputSampleRate is the rate of the original audio, informational only.
+	InputSampleRate int
+}
+
+// OggReader reads the Opus packets of an Ogg Opus stream.
+type OggReader struct {
+	r       io.Reader
+	head    Head
+	packets [][]byte
+	partial []byte
+	eos     bool
+}
+
+// NewOggReader reads the headers of an Ogg Opus stream.
+func NewOggReader(r io.Reader) (*OggReader, error) {
+	o := &OggReader{r: r}
+
+	head, err := o.ReadPacket()
+	if err != nil {
+		return nil, fmt.Errorf("read opus head: %w", err)
+	}
+	if len(head) < 19 || !bytes.HasPrefix(head, opusHead) {
+		return nil, errors.New("not an ogg opus stream")
+	}
+	o.head = Head{
+		Channels:        int(head[9]),
+		PreSkip:         int(binary.LittleEndian.Uint16(head[10:])),
+		InputSampleRate: int(binary.LittleEndian.Uint32(head[12:])),
+	}
+
+	tags, err := o.ReadPacket()
+	if err != nil {
+		return nil, fmt.Errorf("read opus tags: %w", err)
+	}
+	if !bytes.HasPrefix(tags, opusTags) {
+		return nil, errors.New("missing opus tags")
+	}
+
+	return o, nil
+}
+
+// Head returns the identification header of the stream.
+func (o *OggReader) Head() Head {
+	return o.head
+}
+
+// ReadPacket returns the next Opus packet, or io.EOF at the end of the stream.
+func (o *OggReader) ReadPacket() ([]byte, error) {
+	for len(o.packets) == 0 {
+		if o.eos {
+			return nil, io.EOF
+		}
+		if err := o.readPage(); err != nil {
+			return nil, err
+		}
+	}
+
+	p := o.packets[0]
+	o.packets = o.packets[1:]
+	return p, nil
+}
+
+func (o *OggReader) readPage() error {
+	var header [oggHeaderSize]byte
+	if _, err := io.ReadFull(o.r, header[:]); err != nil {
+		if errors.Is(err, io.EOF) && len(o.partial) == 0 {
+			return io.EOF
+		}
+		return fmt.Errorf("read ogg page: %w", err)
+	}
+	if !bytes.Equal(header[:4], oggCapture) {
+		return errors.New("invalid ogg page")
+	}
+
+	segments := make([]byte, header[26])
+	if _, err := io.ReadFull(o.r, segments); err != nil {
+		return fmt.Errorf("read ogg page: %w", err)
+	}
+
+	var size int
+	for _, s := range segments {
+		size += int(s)
+	}
+	body := make([]byte, size)
+	if _, err := io.ReadFull(o.r, body); err != nil {
+		return fmt.Errorf("read ogg page: %w", err)
+	}
+
+	crc := binary.LittleEndian.Uint32(header[22:])
+	binary.LittleEndian.PutUint32(header[22:], 0)
+	sum := oggChecksum(0, header[:])
+	sum = oggChecksum(sum, segments)
+	if oggChecksum(sum, body) != crc {
+		return errors.New("ogg page checksum mismatch")
+	}
+
+	if header[5]&oggContinued == 0 {
+		o.partial = nil
+	}
+	o.eos = header[5]&oggEOS != 0
+
+	// a lacing value below 255 ends a packet
+	for _, s := range segments {
+		o.partial = append(o.partial, body[:s]...)
+		body = body[s:]
+		if s < 255 {
+			o.packets = append(o.packets, o.partial)
+			o.partial = nil
+		}
+	}
+
+	return nil
+}
+
+// OggWriter writes Opus packets as an Ogg Opus stream with one packet per
+// page.
+type OggWriter struct {
+	w       io.Writer
+	serial  uint32
+	seq     uint32
+	granule uint64
+	pending []byte
+}
+
+// NewOggWriter writes the headers of an Ogg Opus stream of mono audio.
+// sampleRate is the rate of the encoded audio, stored for information.
+// preSkip is the lookahead of the encoder in samples at 48 kHz, which players
+// discard at the start, DefaultPreSkip for libopus.
+func NewOggWriter(w io.Writer, sampleRate, preSkip int) (*OggWriter, error) {
+	if preSkip < 0 || preSkip > 0xffff {
+		return nil, fmt.Errorf("invalid pre-skip: %d", preSkip)
+	}
+
+	o := &OggWriter{w: w, serial: 0x6f707573}
+
+	head := make([]byte, 19)
+	copy(head, opusHead)
+	head[8] = 1 // version
+	head[9] = 1 // channels
+	binary.LittleEndian.PutUint16(head[10:], uint16(preSkip))
+	binary.LittleEndian.PutUint32(head[12:], uint32(sampleRate))
+	if err := o.writePage(head, oggBOS); err != nil {
+		return nil, err
+	}
+
+	vendor := "openairt-go"
+	tags := make([]byte, 0, 16+len(vendor))
+	tags = append(tags, opusTags...)
+	tags = binary.LittleEndian.AppendUint32(tags, uint32(len(vendor)))
+	tags = append(tags, vendor...)
+	tags = binary.LittleEndian.AppendUint32(tags, 0)
+	if err := o.writePage(tags, 0); err != nil {
+		return nil, err
+	}
+
+	return o, nil
+}
+
+// WritePacket adds an Opus packet to the stream.
+func (o *OggWriter) WritePacket(packet []byte) error {
+	n, err := PacketSamples(packet)
+	if err != nil {
+		return err
+	}
+
+	// the last packet is held back to mark its page as end of stream
+	if o.pending != nil {
+		if err := o.writePage(o.pending, 0); err != nil {
+			return err
+		}
+	}
+
+	o.granule += uint64(n)
+	o.pending = append(o.pending[:0:0], packet...)
+	return nil
+}
+
+// Close ends the stream. It does not close the underlying writer.
+func (o *OggWriter) Close() error {
+	err := o.writePage(o.pending, oggEOS)
+	o.pending = nil
+	return err
+}
+
+func (o *OggWriter) writePage(packet []byte, flags byte) error {
+	lacing := make([]byte, len(packet)/255+1)
+	for i := range lacing {
+		lacing[i] = 255
+	}
+	lacing[len(lacing)-1] = byte(len(packet) % 255)
+	if len(lacing) > 255 {
+		return fmt.Errorf("packet of %d bytes too large for a single page", len(packet))
+	}
+
+	page := make([]byte, oggHeaderSize, oggHeaderSize+len(lacing)+len(packet))
+	copy(page, oggCapture)
+	page[5] = flags
+	binary.LittleEndian.PutUint64(page[6:], o.granule)
+	binary.LittleEndian.PutUint32(page[14:], o.serial)
+	binary.LittleEndian.PutUint32(page[18:], o.seq)
+	page[26] = byte(len(lacing))
+	page = append(page, lacing...)
+	page = append(page, packet...)
+	binary.LittleEndian.PutUint32(page[22:], oggChecksum(0, page))
+
+	o.seq++
+	_, err := o.w.Write(page)
+	return err
+}
+
+// oggCRCTable builds the table of the CRC-32 used by Ogg: polynomial
+// 0x04c11db7, not reflected, zero initial value.
+func oggCRCTable() *[256]uint32 {
+	var t [256]uint32
+	for i := range t {
+		r := uint32(i) << 24
+		for range 8 {
+			if r&0x80000000 != 0 {
+				r = r<<1 ^ 0x04c11db7
+			} else {
+				r <<= 1
+			}
+		}
+		t[i] = r
+	}
+	return &t
+}
+
+func oggChecksum(crc uint32, p []byte) uint32 {
+	for _, b := range p {
+		crc = crc<<8 ^ oggCRC[byte(crc>>24)^b]
+	}
+	return crc
+}
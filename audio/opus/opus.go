@@ -0,0 +1,85 @@
+// Package opus adapts Client.Audio to Opus audio as delivered by browsers and
+// WebRTC.
+//
+// The package handles Ogg and RTP framing of Opus packets and decodes them in
+// pure Go. Encoding uses libopus through cgo and is only built with the
+// libopus build tag; without it NewEncoder fails and the Adapter can only
+// decode:
+//
+//	enc, _ := opus.NewEncoder(24_000) // go build -tags libopus
+//	dec, _ := opus.NewDecoder(24_000)
+//	a, _ := opus.NewAdapter(client.Audio(), 24_000, enc, dec)
+//
+// Any other Encoder or Decoder can be used instead, the method sets match the
+// libopus bindings of gopkg.in/hraban/opus.v2.
+package opus
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// SampleRate is the rate Opus packet durations and Ogg granule positions
+	// are counted in.
+	SampleRate = 48_000
+	// FrameDuration is the standard packetization of WebRTC.
+	FrameDuration = 20 * time.Millisecond
+	// MaxPacketSize is the largest size of a single Opus packet.
+	MaxPacketSize = 1275 * 3
+	// DefaultPreSkip is the lookahead of libopus in samples at 48 kHz for the
+	// VoIP and audio applications (OPUS_GET_LOOKAHEAD), the pre-skip of the
+	// streams it encodes.
+	DefaultPreSkip = 312
+)
+
+// Encoder encodes a frame of mono PCM16 samples into an Opus packet.
+type Encoder interface {
+	Encode(pcm []int16, data []byte) (int, error)
+}
+
+// Decoder decodes an Opus packet into mono PCM16 samples.
+type Decoder interface {
+	Decode(data []byte, pcm []int16) (int, error)
+}
+
+var ErrInvalidPacket = errors.New("invalid opus packet")
+
+// frameSamples are the frame durations of the TOC configs in samples at
+// 48 kHz.
+var frameSamples = [32]int{
+	// SILK: 10, 20, 40, 60 ms
+	480, 960, 1920, 2880, 480, 960, 1920, 2880, 480, 960, 1920, 2880,
+	// hybrid: 10, 20 ms
+	480, 960, 480, 960,
+	// CELT: 2.5, 5, 10, 20 ms
+	120, 240, 480, 960, 120, 240, 480, 960, 120, 240, 480, 960, 120, 240, 480, 960,
+}
+
+// PacketSamples returns the number of samples per channel a packet decodes
+// to at 48 kHz, read from its TOC byte (RFC 6716, section 3.1).
+func PacketSamples(packet []byte) (int, error) {
+	if len(packet) == 0 {
+		return 0, ErrInvalidPacket
+	}
+
+	toc := packet[0]
+	frames := 1
+	switch toc & 0x3 {
+	case 1, 2:
+		frames = 2
+	case 3:
+		if len(packet) < 2 {
+			return 0, ErrInvalidPacket
+		}
+		frames = int(packet[1] & 0x3f)
+	}
+
+	n := frames * frameSamples[toc>>3]
+	if frames == 0 || n > SampleRate*120/1000 {
+		return 0, fmt.Errorf("%w: %d frames of %d samples", ErrInvalidPacket, frames, frameSamples[toc>>3])
+	}
+
+	return n, nil
+}
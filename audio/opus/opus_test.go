@@ -0,0 +1,225 @@
+package opus
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/stretchr/testify/require"
+	"io"
+	"os"
+	"testing"
+)
+
+// silence.opus and split.opus are built by hand: they contain CELT packets of
+// digital silence (0xF8 0xFF 0xFE, as sent by WebRTC endpoints during silence)
+// and a packet spanning two pages. The framing tests use them with testCodec,
+// whose output is predictable; codec_test.go decodes recorded speech.
+
+var silencePacket = []byte{0xF8, 0xFF, 0xFE}
+
+// testCodec stands in for libopus. It decodes a packet to its duration in
+// samples, each set to the packet length, and encodes a frame to a CELT
+// 20 ms TOC byte followed by the first sample.
+type testCodec struct {
+	rate int
+}
+
+func (c testCodec) Decode(data []byte, pcm []int16) (int, error) {
+	n, err := PacketSamples(data)
+	if err != nil {
+		return 0, err
+	}
+	n = n * c.rate / SampleRate
+	for i := range pcm[:n] {
+		pcm[i] = int16(len(data))
+	}
+	return n, nil
+}
+
+func (c testCodec) Encode(pcm []int16, data []byte) (int, error) {
+	data[0] = 0xF8
+	binary.LittleEndian.PutUint16(data[1:], uint16(pcm[0]))
+	return 3, nil
+}
+
+func TestPacketSamples(t *testing.T) {
+	for _, tc := range []struct {
+		packet []byte
+		n      int
+	}{
+		{silencePacket, 960},
+		{[]byte{0x08}, 960},        // SILK NB 20ms
+		{[]byte{0x18}, 2880},       // SILK NB 60ms
+		{[]byte{0x78}, 960},        // hybrid FB 20ms
+		{[]byte{0xE0}, 120},        // CELT FB 2.5ms
+		{[]byte{0xF1, 0, 0}, 960},  // two 10ms frames
+		{[]byte{0xFB, 0x06}, 5760}, // six 20ms frames, 120ms
+		{[]byte{0xFB, 0x07}, 0},    // more than 120ms
+		{[]byte{0xFB}, 0},          // missing frame count
+		{[]byte{}, 0},
+	} {
+		n, err := PacketSamples(tc.packet)
+		if tc.n == 0 {
+			require.ErrorIs(t, err, ErrInvalidPacket)
+			continue
+		}
+		require.NoError(t, err)
+		require.Equal(t, tc.n, n, "%x", tc.packet)
+	}
+}
+
+func TestOggReader(t *testing.T) {
+	f, err := os.Open("testdata/silence.opus")
+	require.NoError(t, err)
+	defer f.Close()
+
+	o, err := NewOggReader(f)
+	require.NoError(t, err)
+	require.Equal(t, Head{Channels: 1, PreSkip: 312, InputSampleRate: 48_000}, o.Head())
+
+	var packets int
+	for {
+		p, err := o.ReadPacket()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		require.Equal(t, silencePacket, p)
+		packets++
+	}
+	require.Equal(t, 50, packets)
+}
+
+func TestOggReaderContinuedPacket(t *testing.T) {
+	data, err := os.ReadFile("testdata/split.opus")
+	require.NoError(t, err)
+
+	o, err := NewOggReader(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	p, err := o.ReadPacket()
+	require.NoError(t, err)
+	require.Len(t, p, 600)
+	require.Equal(t, byte(0xF8), p[0])
+
+	p, err = o.ReadPacket()
+	require.NoError(t, err)
+	require.Equal(t, []byte{0xF1, 0xAA, 0xBB}, p)
+
+	_, err = o.ReadPacket()
+	require.Equal(t, io.EOF, err)
+
+	// corrupt the body of the last page
+	data[len(data)-1] ^= 0xFF
+	o, err = NewOggReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	_, err = o.ReadPacket()
+	require.ErrorContains(t, err, "checksum")
+}
+
+func TestOggWriter(t *testing.T) {
+	large := append([]byte{0xF8}, bytes.Repeat([]byte{1}, 509)...)
+	packets := [][]byte{silencePacket, large, {0xF1, 0, 0}}
+
+	var buf bytes.Buffer
+	w, err := NewOggWriter(&buf, 24_000, DefaultPreSkip)
+	require.NoError(t, err)
+	for _, p := range packets {
+		require.NoError(t, w.WritePacket(p))
+	}
+	require.NoError(t, w.Close())
+
+	// the last page ends the stream and has the total duration as granule
+	last := bytes.LastIndex(buf.Bytes(), oggCapture)
+	require.Equal(t, byte(oggEOS), buf.Bytes()[last+5])
+	require.Equal(t, uint64(3*960), binary.LittleEndian.Uint64(buf.Bytes()[last+6:]))
+
+	o, err := NewOggReader(&buf)
+	require.NoError(t, err)
+	require.Equal(t, Head{Channels: 1, PreSkip: DefaultPreSkip, InputSampleRate: 24_000}, o.Head())
+	for _, want := range packets {
+		p, err := o.ReadPacket()
+		require.NoError(t, err)
+		require.Equal(t, want, p)
+	}
+	_, err = o.ReadPacket()
+	require.Equal(t, io.EOF, err)
+
+	require.ErrorIs(t, w.WritePacket(nil), ErrInvalidPacket)
+
+	_, err = NewOggWriter(&buf, 24_000, -1)
+	require.Error(t, err)
+}
+
+func TestRTP(t *testing.T) {
+	p := &Packetizer{PayloadType: 111, SSRC: 42}
+
+	first, err := p.Packetize(silencePacket)
+	require.NoError(t, err)
+	second, err := p.Packetize([]byte{0xE0, 0x01})
+	require.NoError(t, err)
+
+	require.Equal(t, byte(0x80|111), first[1])
+	require.Equal(t, byte(111), second[1])
+	require.Equal(t, uint16(1), binary.BigEndian.Uint16(second[2:]))
+	require.Equal(t, uint32(960), binary.BigEndian.Uint32(second[4:]))
+	require.Equal(t, uint32(42), binary.BigEndian.Uint32(second[8:]))
+
+	payload, err := RTPPayload(first)
+	require.NoError(t, err)
+	require.Equal(t, silencePacket, payload)
+
+	// one CSRC, a header extension of one word and two bytes of padding
+	packet := []byte{
+		0xB1, 111, 0, 1, 0, 0, 0, 0, 0, 0, 0, 42,
+		0, 0, 0, 7,
+		0xBE, 0xDE, 0, 1, 0x10, 0xFF, 0, 0,
+		0xF8, 0xFF, 0xFE,
+		0, 2,
+	}
+	payload, err = RTPPayload(packet)
+	require.NoError(t, err)
+	require.Equal(t, silencePacket, payload)
+
+	_, err = RTPPayload(packet[:10])
+	require.ErrorIs(t, err, ErrInvalidRTP)
+}
+
+func TestAdapter(t *testing.T) {
+	_, err := NewAdapter(&bytes.Buffer{}, 44_100, nil, nil)
+	require.Error(t, err)
+
+	codec := testCodec{rate: 24_000}
+	var audio bytes.Buffer
+	a, err := NewAdapter(&audio, 24_000, codec, codec)
+	require.NoError(t, err)
+
+	f, err := os.Open("testdata/silence.opus")
+	require.NoError(t, err)
+	defer f.Close()
+
+	// 1s at 24 kHz without the pre-skip of 312 samples at 48 kHz
+	require.NoError(t, a.WriteOgg(f))
+	require.Equal(t, (24_000-156)*2, audio.Len())
+	require.Equal(t, int16(3), int16(binary.LittleEndian.Uint16(audio.Bytes())))
+
+	rtp, err := (&Packetizer{}).Packetize(silencePacket)
+	require.NoError(t, err)
+	require.NoError(t, a.WriteRTP(rtp))
+	require.Equal(t, (24_000-156+480)*2, audio.Len())
+
+	// 20ms frames of 480 samples are encoded to packets
+	audio.Reset()
+	pcm := make([]byte, 480*2*2+10)
+	binary.LittleEndian.PutUint16(pcm, 7)
+	binary.LittleEndian.PutUint16(pcm[480*2:], 9)
+	audio.Write(pcm)
+
+	packet, err := a.ReadPacket()
+	require.NoError(t, err)
+	require.Equal(t, []byte{0xF8, 7, 0}, packet)
+	packet, err = a.ReadPacket()
+	require.NoError(t, err)
+	require.Equal(t, []byte{0xF8, 9, 0}, packet)
+	_, err = a.ReadPacket()
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,73 @@
+package opus
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+const rtpHeaderSize = 12
+
+var ErrInvalidRTP = errors.New("invalid rtp packet")
+
+// RTPPayload returns the Opus packet carried by an RTP packet (RFC 7587).
+// The returned slice shares memory with packet.
+func RTPPayload(packet []byte) ([]byte, error) {
+	if len(packet) < rtpHeaderSize || packet[0]>>6 != 2 {
+		return nil, ErrInvalidRTP
+	}
+
+	offset := rtpHeaderSize + int(packet[0]&0x0f)*4
+	if packet[0]&0x10 != 0 {
+		// header extension: 16 bit profile, 16 bit length in words
+		if len(packet) < offset+4 {
+			return nil, ErrInvalidRTP
+		}
+		offset += 4 + int(binary.BigEndian.Uint16(packet[offset+2:]))*4
+	}
+
+	end := len(packet)
+	if packet[0]&0x20 != 0 {
+		end -= int(packet[end-1])
+	}
+	if offset > end {
+		return nil, ErrInvalidRTP
+	}
+
+	return packet[offset:end], nil
+}
+
+// Packetizer wraps Opus packets into RTP packets of one stream.
+type Packetizer struct {
+	PayloadType uint8
+	SSRC        uint32
+
+	seq       uint16
+	timestamp uint32
+	started   bool
+}
+
+// Packetize returns an RTP packet carrying an Opus packet. The timestamp
+// advances by the duration of the packet in the 48 kHz RTP clock of Opus.
+func (p *Packetizer) Packetize(payload []byte) ([]byte, error) {
+	n, err := PacketSamples(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	packet := make([]byte, rtpHeaderSize, rtpHeaderSize+len(payload))
+	packet[0] = 2 << 6
+	packet[1] = p.PayloadType & 0x7f
+	if !p.started {
+		// the marker bit flags the first packet of a talkspurt
+		packet[1] |= 0x80
+		p.started = true
+	}
+	binary.BigEndian.PutUint16(packet[2:], p.seq)
+	binary.BigEndian.PutUint32(packet[4:], p.timestamp)
+	binary.BigEndian.PutUint32(packet[8:], p.SSRC)
+
+	p.seq++
+	p.timestamp += uint32(n)
+
+	return append(packet, payload...), nil
+}
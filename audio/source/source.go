@@ -0,0 +1,128 @@
+// Package source streams audio files into Client.Audio, e.g. to test prompts
+// without a microphone.
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/codewandler/openairt-go"
+	"github.com/codewandler/openairt-go/audio/wav"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Options controls how audio is streamed.
+type Options struct {
+	// SampleRate is the rate of the destination, i.e. the one set with
+	// openairt.WithSampleRate. Raw PCM files are expected at this rate.
+	SampleRate int
+	// Speed is the pace relative to real time, 2 streams twice as fast. Zero
+	// streams as fast as the destination accepts the audio.
+	Speed float64
+	// Silence is appended to the audio, so server VAD detects the end of the
+	// turn.
+	Silence time.Duration
+	// Chunk is the duration of each write, 20ms by default.
+	Chunk time.Duration
+}
+
+// Stream writes mono PCM16 audio from src to dst, paced as set in opts.
+func Stream(ctx context.Context, dst io.Writer, src io.Reader, opts Options) error {
+	if opts.SampleRate <= 0 {
+		return errors.New("sample rate required")
+	}
+	if opts.Chunk <= 0 {
+		opts.Chunk = 20 * time.Millisecond
+	}
+
+	bytesPerSecond := float64(opts.SampleRate * 2)
+	chunk := make([]byte, int(bytesPerSecond*opts.Chunk.Seconds())&^1)
+	silence := int64(bytesPerSecond*opts.Silence.Seconds()) &^ 1
+
+	src = io.MultiReader(src, io.LimitReader(zeros{}, silence))
+
+	start := time.Now()
+	var sent int64
+
+	for {
+		n, err := io.ReadFull(src, chunk)
+		if n > 0 {
+			if _, err := dst.Write(chunk[:n]); err != nil {
+				return err
+			}
+			sent += int64(n)
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if opts.Speed <= 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			continue
+		}
+
+		due := start.Add(time.Duration(float64(sent) / bytesPerSecond / opts.Speed * float64(time.Second)))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Until(due)):
+		}
+	}
+}
+
+// StreamFile streams a WAV file or a file of raw mono PCM16 at
+// opts.SampleRate. WAV files at other rates are resampled and multichannel
+// audio is mixed down to mono.
+func StreamFile(ctx context.Context, dst io.Writer, name string, opts Options) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var src io.Reader = f
+	if strings.EqualFold(filepath.Ext(name), ".wav") {
+		w, err := wav.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		src = w.Mono()
+
+		if w.SampleRate != opts.SampleRate {
+			if src, err = resample(src, w.SampleRate, opts.SampleRate); err != nil {
+				return err
+			}
+		}
+	}
+
+	return Stream(ctx, dst, src, opts)
+}
+
+// resample converts the complete audio up front, prompts are short.
+func resample(src io.Reader, from, to int) (io.Reader, error) {
+	r, err := openairt.NewResampler(from, to)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(r, src); err != nil {
+		return nil, err
+	}
+	r.Flush()
+	return r, nil
+}
+
+type zeros struct{}
+
+func (zeros) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
@@ -0,0 +1,66 @@
+package source
+
+import (
+	"bytes"
+	"context"
+	"github.com/codewandler/openairt-go/audio/wav"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStream(t *testing.T) {
+	var dst bytes.Buffer
+	src := bytes.Repeat([]byte{1, 0}, 1_000)
+
+	require.NoError(t, Stream(context.Background(), &dst, bytes.NewReader(src), Options{
+		SampleRate: 8_000,
+		Silence:    100 * time.Millisecond,
+	}))
+	require.Equal(t, 2_000+1_600, dst.Len())
+	require.Equal(t, src, dst.Bytes()[:2_000])
+	require.Equal(t, make([]byte, 1_600), dst.Bytes()[2_000:])
+}
+
+func TestStreamRealTime(t *testing.T) {
+	var dst bytes.Buffer
+	start := time.Now()
+
+	// 1s of audio at 5 times real time
+	require.NoError(t, Stream(context.Background(), &dst, bytes.NewReader(make([]byte, 16_000)), Options{
+		SampleRate: 8_000,
+		Speed:      5,
+	}))
+	require.Equal(t, 16_000, dst.Len())
+	require.GreaterOrEqual(t, time.Since(start), 190*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := Stream(ctx, &dst, bytes.NewReader(make([]byte, 16_000)), Options{SampleRate: 8_000, Speed: 1})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestStreamFile(t *testing.T) {
+	dir := t.TempDir()
+
+	name := filepath.Join(dir, "in.wav")
+	f, err := wav.Create(name, 48_000, 1)
+	require.NoError(t, err)
+	_, err = f.Write(make([]byte, 48_000*2))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	// WAV files are converted to the sample rate of the destination
+	var dst bytes.Buffer
+	require.NoError(t, StreamFile(context.Background(), &dst, name, Options{SampleRate: 24_000}))
+	require.Equal(t, 24_000*2, dst.Len())
+
+	// raw files are used as is
+	raw := filepath.Join(dir, "in.pcm")
+	require.NoError(t, os.WriteFile(raw, make([]byte, 320), 0o644))
+	dst.Reset()
+	require.NoError(t, StreamFile(context.Background(), &dst, raw, Options{SampleRate: 24_000}))
+	require.Equal(t, 320, dst.Len())
+}
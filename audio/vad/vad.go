@@ -0,0 +1,257 @@
+// Package vad detects voice activity in PCM16 audio.
+//
+// A frame counts as voice if its energy is well above the adaptive noise
+// floor and its spectrum looks like speech: not flat like noise, with most
+// energy in the speech band. Short bursts are ignored and short pauses
+// bridged, see Config.
+package vad
+
+import (
+	"encoding/binary"
+	"math"
+	"math/cmplx"
+	"time"
+)
+
+// speechLow and speechHigh bound the band holding most energy of voice.
+const (
+	speechLow  = 80
+	speechHigh = 4000
+)
+
+// Event is a change of the speech state.
+type Event int
+
+const (
+	None Event = iota
+	SpeechStarted
+	SpeechStopped
+)
+
+// Config configures a Detector. Zero values use the defaults.
+type Config struct {
+	// SampleRate of the audio, required.
+	SampleRate int
+	// Frame is the analysis window, 20ms by default.
+	Frame time.Duration
+	// Threshold is how far the energy of voice is above the noise floor in
+	// dB, 12 by default.
+	Threshold float64
+	// MinLevel is the energy in dBFS below which audio is never voice, -50
+	// by default.
+	MinLevel float64
+	// MaxFlatness is the spectral flatness (0 tonal, 1 white noise) above
+	// which a frame is not voice, 0.3 by default.
+	MaxFlatness float64
+	// MinSpeech is the duration of voice that starts speech, 60ms by default.
+	MinSpeech time.Duration
+	// Hangover is the duration of silence that stops speech, 500ms by
+	// default.
+	Hangover time.Duration
+}
+
+func (c *Config) defaults() {
+	if c.Frame <= 0 {
+		c.Frame = 20 * time.Millisecond
+	}
+	if c.Threshold == 0 {
+		c.Threshold = 12
+	}
+	if c.MinLevel == 0 {
+		c.MinLevel = -50
+	}
+	if c.MaxFlatness == 0 {
+		c.MaxFlatness = 0.3
+	}
+	if c.MinSpeech <= 0 {
+		c.MinSpeech = 60 * time.Millisecond
+	}
+	if c.Hangover <= 0 {
+		c.Hangover = 500 * time.Millisecond
+	}
+}
+
+// Detector tracks the speech state of a stream of audio.
+type Detector struct {
+	config    Config
+	frameSize int
+	minVoice  int
+	hangover  int
+
+	partial  []byte
+	floor    float64
+	speaking bool
+	voiced   int // consecutive voice frames
+	silent   int // consecutive frames without voice
+
+	fft []complex128
+}
+
+// New creates a Detector.
+func New(config Config) *Detector {
+	config.defaults()
+
+	frameSize := int(float64(config.SampleRate) * config.Frame.Seconds())
+	frames := func(d time.Duration) int {
+		return max(1, int(math.Ceil(float64(d)/float64(config.Frame))))
+	}
+
+	n := 1
+	for n < frameSize {
+		n <<= 1
+	}
+
+	d := &Detector{
+		config:    config,
+		frameSize: frameSize,
+		minVoice:  frames(config.MinSpeech),
+		hangover:  frames(config.Hangover),
+		fft:       make([]complex128, n),
+	}
+	d.Reset()
+
+	return d
+}
+
+// Reset forgets the speech state and the noise floor.
+func (d *Detector) Reset() {
+	d.partial = d.partial[:0]
+	d.floor = d.config.MinLevel - d.config.Threshold
+	d.speaking = false
+	d.voiced = 0
+	d.silent = 0
+}
+
+// Speaking reports whether speech is in progress.
+func (d *Detector) Speaking() bool {
+	return d.speaking
+}
+
+// Write analyzes mono PCM16 audio and returns the state change it caused, if
+// any. Audio is analyzed in frames, the remainder is kept for the next call.
+func (d *Detector) Write(p []byte) Event {
+	evt := None
+
+	d.partial = append(d.partial, p...)
+	for len(d.partial) >= d.frameSize*2 {
+		if e := d.frame(d.partial[:d.frameSize*2]); e != None {
+			evt = e
+		}
+		d.partial = d.partial[d.frameSize*2:]
+	}
+
+	// keep the remainder at the start of the buffer
+	d.partial = append(d.partial[:0:0], d.partial...)
+
+	return evt
+}
+
+func (d *Detector) frame(p []byte) Event {
+	voice := d.isVoice(p)
+
+	if voice {
+		d.voiced++
+		d.silent = 0
+	} else {
+		d.voiced = 0
+		d.silent++
+	}
+
+	switch {
+	case !d.speaking && d.voiced >= d.minVoice:
+		d.speaking = true
+		return SpeechStarted
+	case d.speaking && d.silent >= d.hangover:
+		d.speaking = false
+		return SpeechStopped
+	}
+	return None
+}
+
+// isVoice classifies a frame and updates the noise floor.
+func (d *Detector) isVoice(p []byte) bool {
+	samples := len(p) / 2
+
+	var sum float64
+	for i := range d.fft {
+		var v float64
+		if i < samples {
+			v = float64(int16(binary.LittleEndian.Uint16(p[i*2:]))) / 32768
+		}
+		sum += v * v
+		// a Hann window keeps tones from leaking into other bins
+		w := 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(samples))
+		if i >= samples {
+			w = 0
+		}
+		d.fft[i] = complex(v*w, 0)
+	}
+	level := 10 * math.Log10(sum/float64(samples)+1e-12)
+
+	loud := level > d.config.MinLevel && level > d.floor+d.config.Threshold
+	voice := loud && d.speechLike()
+
+	// the floor follows quiet frames quickly and louder ones slowly, so it
+	// recovers from a change of the background noise
+	switch {
+	case level < d.floor:
+		d.floor = 0.7*d.floor + 0.3*level
+	case !voice:
+		d.floor = 0.98*d.floor + 0.02*level
+	}
+
+	return voice
+}
+
+// speechLike checks the spectrum of the frame in d.fft.
+func (d *Detector) speechLike() bool {
+	fft(d.fft)
+
+	n := len(d.fft)
+	binHz := float64(d.config.SampleRate) / float64(n)
+
+	var total, band, logSum float64
+	var bins int
+	for k := 1; k < n/2; k++ {
+		power := real(d.fft[k])*real(d.fft[k]) + imag(d.fft[k])*imag(d.fft[k]) + 1e-12
+		total += power
+		logSum += math.Log(power)
+		bins++
+
+		if hz := float64(k) * binHz; hz >= speechLow && hz <= speechHigh {
+			band += power
+		}
+	}
+
+	flatness := math.Exp(logSum/float64(bins)) / (total / float64(bins))
+
+	return flatness < d.config.MaxFlatness && band/total > 0.5
+}
+
+// fft is an in place radix-2 Cooley-Tukey transform, len(x) is a power of 2.
+func fft(x []complex128) {
+	n := len(x)
+
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j |= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+
+	for size := 2; size <= n; size <<= 1 {
+		w := cmplx.Exp(complex(0, -2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			wk := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				a, b := x[start+k], x[start+k+size/2]*wk
+				x[start+k], x[start+k+size/2] = a+b, a-b
+				wk *= w
+			}
+		}
+	}
+}
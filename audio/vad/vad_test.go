@@ -0,0 +1,121 @@
+package vad
+
+import (
+	"encoding/binary"
+	"github.com/stretchr/testify/require"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+const rate = 16_000
+
+// voice returns a vowel-like signal: a 150 Hz fundamental with harmonics.
+func voice(d time.Duration, amplitude float64) []float64 {
+	out := make([]float64, int(d.Seconds()*rate))
+	for i := range out {
+		t := float64(i) / rate
+		for h := 1; h <= 10; h++ {
+			out[i] += amplitude / float64(h) * math.Sin(2*math.Pi*150*float64(h)*t)
+		}
+	}
+	return out
+}
+
+func noise(d time.Duration, amplitude float64, seed int64) []float64 {
+	rnd := rand.New(rand.NewSource(seed))
+	out := make([]float64, int(d.Seconds()*rate))
+	for i := range out {
+		out[i] = amplitude * rnd.NormFloat64()
+	}
+	return out
+}
+
+func pcm(parts ...[]float64) []byte {
+	var b []byte
+	for _, part := range parts {
+		for _, v := range part {
+			v = math.Max(-1, math.Min(1, v))
+			b = binary.LittleEndian.AppendUint16(b, uint16(int16(v*32767)))
+		}
+	}
+	return b
+}
+
+// run feeds audio in 10ms chunks and returns the times of the events.
+func run(d *Detector, audio []byte) map[Event][]time.Duration {
+	events := map[Event][]time.Duration{}
+	chunk := rate / 100 * 2
+	for i := 0; i < len(audio); i += chunk {
+		if e := d.Write(audio[i:min(i+chunk, len(audio))]); e != None {
+			at := time.Duration(i+chunk) * time.Second / (rate * 2)
+			events[e] = append(events[e], at)
+		}
+	}
+	return events
+}
+
+func TestDetectorSpeech(t *testing.T) {
+	d := New(Config{SampleRate: rate})
+
+	audio := pcm(
+		noise(500*time.Millisecond, 0.001, 1),
+		voice(time.Second, 0.2),
+		noise(time.Second, 0.001, 2),
+	)
+
+	events := run(d, audio)
+	require.Len(t, events[SpeechStarted], 1)
+	require.Len(t, events[SpeechStopped], 1)
+	require.InDelta(t, 560*time.Millisecond, events[SpeechStarted][0], float64(30*time.Millisecond))
+	require.InDelta(t, 2000*time.Millisecond, events[SpeechStopped][0], float64(30*time.Millisecond))
+	require.False(t, d.Speaking())
+}
+
+func TestDetectorIgnoresNoise(t *testing.T) {
+	d := New(Config{SampleRate: rate})
+
+	// loud broadband noise, silence and a single click
+	audio := pcm(
+		noise(time.Second, 0.001, 1),
+		noise(time.Second, 0.2, 2),
+		make([]float64, rate/2),
+		voice(20*time.Millisecond, 0.5),
+		make([]float64, rate/2),
+	)
+
+	require.Empty(t, run(d, audio))
+}
+
+func TestDetectorPause(t *testing.T) {
+	d := New(Config{SampleRate: rate, Hangover: 300 * time.Millisecond})
+
+	// a short pause is bridged, a long one ends the speech
+	audio := pcm(
+		voice(500*time.Millisecond, 0.2),
+		noise(200*time.Millisecond, 0.001, 1),
+		voice(500*time.Millisecond, 0.2),
+		noise(400*time.Millisecond, 0.001, 2),
+	)
+
+	events := run(d, audio)
+	require.Len(t, events[SpeechStarted], 1)
+	require.Len(t, events[SpeechStopped], 1)
+}
+
+func TestFFT(t *testing.T) {
+	x := make([]complex128, 64)
+	for i := range x {
+		x[i] = complex(math.Cos(2*math.Pi*5*float64(i)/64), 0)
+	}
+	fft(x)
+
+	for k, v := range x {
+		want := 0.0
+		if k == 5 || k == 59 {
+			want = 32
+		}
+		require.InDelta(t, want, math.Hypot(real(v), imag(v)), 1e-9, "bin %d", k)
+	}
+}
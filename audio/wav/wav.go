@@ -0,0 +1,296 @@
+// Package wav reads and writes PCM16 WAV files.
+package wav
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	headerSize = 44
+	formatPCM  = 1
+)
+
+// Reader reads the samples of a PCM16 WAV file, interleaved if it has more
+// than one channel.
+type Reader struct {
+	r          io.Reader
+	SampleRate int
+	Channels   int
+	remaining  int64
+}
+
+// NewReader parses the header of a WAV stream up to its data chunk.
+func NewReader(r io.Reader) (*Reader, error) {
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil {
+		return nil, fmt.Errorf("read wav header: %w", err)
+	}
+	if string(riff[:4]) != "RIFF" || string(riff[8:]) != "WAVE" {
+		return nil, errors.New("not a wav file")
+	}
+
+	w := &Reader{r: r}
+	var hasFormat bool
+
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(r, chunk[:]); err != nil {
+			return nil, fmt.Errorf("read wav chunk: %w", err)
+		}
+		size := int64(binary.LittleEndian.Uint32(chunk[4:]))
+
+		switch string(chunk[:4]) {
+		case "fmt ":
+			if size < 16 {
+				return nil, errors.New("invalid wav format chunk")
+			}
+			format := make([]byte, size+size%2)
+			if _, err := io.ReadFull(r, format); err != nil {
+				return nil, fmt.Errorf("read wav format: %w", err)
+			}
+			if tag := binary.LittleEndian.Uint16(format); tag != formatPCM {
+				return nil, fmt.Errorf("unsupported wav format: %d", tag)
+			}
+			if bits := binary.LittleEndian.Uint16(format[14:]); bits != 16 {
+				return nil, fmt.Errorf("unsupported wav bit depth: %d", bits)
+			}
+			w.Channels = int(binary.LittleEndian.Uint16(format[2:]))
+			w.SampleRate = int(binary.LittleEndian.Uint32(format[4:]))
+			hasFormat = true
+		case "data":
+			if !hasFormat {
+				return nil, errors.New("wav data before format chunk")
+			}
+			w.remaining = size
+			return w, nil
+		default:
+			// chunks are padded to an even size
+			if _, err := io.CopyN(io.Discard, r, size+size%2); err != nil {
+				return nil, fmt.Errorf("skip wav chunk: %w", err)
+			}
+		}
+	}
+}
+
+// Read reads PCM16 samples.
+func (w *Reader) Read(p []byte) (int, error) {
+	if w.remaining <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > w.remaining {
+		p = p[:w.remaining]
+	}
+
+	n, err := w.r.Read(p)
+	w.remaining -= int64(n)
+	if errors.Is(err, io.EOF) && w.remaining > 0 {
+		// tolerate files whose header was never finalized
+		w.remaining = 0
+	}
+	return n, err
+}
+
+// Mono returns a reader of the samples mixed down to one channel.
+func (w *Reader) Mono() io.Reader {
+	if w.Channels <= 1 {
+		return w
+	}
+	return &monoReader{r: w, channels: w.Channels}
+}
+
+type monoReader struct {
+	r        io.Reader
+	channels int
+	buf      []byte
+}
+
+func (m *monoReader) Read(p []byte) (int, error) {
+	frames := len(p) / 2
+	if frames == 0 {
+		return 0, nil
+	}
+	frameSize := 2 * m.channels
+	if cap(m.buf) < frames*frameSize {
+		m.buf = make([]byte, frames*frameSize)
+	}
+
+	n, err := io.ReadFull(m.r, m.buf[:frames*frameSize])
+	n -= n % frameSize
+	for i := 0; i < n/frameSize; i++ {
+		var sum int
+		for c := range m.channels {
+			sum += int(int16(binary.LittleEndian.Uint16(m.buf[i*frameSize+c*2:])))
+		}
+		binary.LittleEndian.PutUint16(p[i*2:], uint16(int16(sum/m.channels)))
+	}
+
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = nil
+		if n == 0 {
+			err = io.EOF
+		}
+	}
+	return n / m.channels, err
+}
+
+// Writer writes PCM16 samples, interleaved if there is more than one
+// channel, to a WAV file. Close updates the sizes in the header.
+type Writer struct {
+	w          io.WriteSeeker
+	sampleRate int
+	channels   int
+	size       int64
+	cues       []Cue
+}
+
+// Cue marks a position in the audio, e.g. the start of a segment.
+type Cue struct {
+	// Frame is the position in samples per channel.
+	Frame int64
+	Label string
+}
+
+// NewWriter writes the header of a PCM16 WAV file.
+func NewWriter(w io.WriteSeeker, sampleRate, channels int) (*Writer, error) {
+	if channels < 1 {
+		return nil, fmt.Errorf("invalid channel count: %d", channels)
+	}
+
+	wr := &Writer{w: w, sampleRate: sampleRate, channels: channels}
+	if _, err := w.Write(wr.header(0)); err != nil {
+		return nil, err
+	}
+	return wr, nil
+}
+
+// header returns the RIFF, format and data chunk headers. extra is the size
+// of the chunks following the data.
+func (w *Writer) header(extra int64) []byte {
+	blockAlign := 2 * w.channels
+
+	h := make([]byte, 0, headerSize)
+	h = append(h, "RIFF"...)
+	h = binary.LittleEndian.AppendUint32(h, uint32(36+w.size+w.size%2+extra))
+	h = append(h, "WAVEfmt "...)
+	h = binary.LittleEndian.AppendUint32(h, 16)
+	h = binary.LittleEndian.AppendUint16(h, formatPCM)
+	h = binary.LittleEndian.AppendUint16(h, uint16(w.channels))
+	h = binary.LittleEndian.AppendUint32(h, uint32(w.sampleRate))
+	h = binary.LittleEndian.AppendUint32(h, uint32(w.sampleRate*blockAlign))
+	h = binary.LittleEndian.AppendUint16(h, uint16(blockAlign))
+	h = binary.LittleEndian.AppendUint16(h, 16)
+	h = append(h, "data"...)
+	h = binary.LittleEndian.AppendUint32(h, uint32(w.size))
+	return h
+}
+
+// Write writes PCM16 samples.
+func (w *Writer) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// AddCue adds a marker, written as cue and label chunks on Close.
+func (w *Writer) AddCue(frame int64, label string) {
+	w.cues = append(w.cues, Cue{Frame: frame, Label: label})
+}
+
+// Cues returns the markers added so far.
+func (w *Writer) Cues() []Cue {
+	return w.cues
+}
+
+// Close writes the cues and the final header. It does not close the
+// underlying writer.
+func (w *Writer) Close() error {
+	cues := w.cueChunks()
+	trailer := cues
+	if w.size%2 == 1 {
+		trailer = append([]byte{0}, cues...)
+	}
+	if _, err := w.w.Write(trailer); err != nil {
+		return err
+	}
+
+	if _, err := w.w.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := w.w.Write(w.header(int64(len(cues)))); err != nil {
+		return err
+	}
+	_, err := w.w.Seek(0, io.SeekEnd)
+	return err
+}
+
+// cueChunks returns a cue chunk with the positions and a LIST adtl chunk
+// with the labels of the cues.
+func (w *Writer) cueChunks() []byte {
+	if len(w.cues) == 0 {
+		return nil
+	}
+
+	cue := binary.LittleEndian.AppendUint32(nil, uint32(len(w.cues)))
+	var labels []byte
+	labels = append(labels, "adtl"...)
+
+	for i, c := range w.cues {
+		id := uint32(i + 1)
+		cue = binary.LittleEndian.AppendUint32(cue, id)
+		cue = binary.LittleEndian.AppendUint32(cue, uint32(c.Frame))
+		cue = append(cue, "data"...)
+		cue = binary.LittleEndian.AppendUint32(cue, 0) // chunk start
+		cue = binary.LittleEndian.AppendUint32(cue, 0) // block start
+		cue = binary.LittleEndian.AppendUint32(cue, uint32(c.Frame))
+
+		text := append([]byte(c.Label), 0)
+		labels = append(labels, "labl"...)
+		labels = binary.LittleEndian.AppendUint32(labels, uint32(4+len(text)))
+		labels = binary.LittleEndian.AppendUint32(labels, id)
+		labels = append(labels, text...)
+		if len(text)%2 == 1 {
+			labels = append(labels, 0)
+		}
+	}
+
+	out := append([]byte("cue "), binary.LittleEndian.AppendUint32(nil, uint32(len(cue)))...)
+	out = append(out, cue...)
+	out = append(out, "LIST"...)
+	out = binary.LittleEndian.AppendUint32(out, uint32(len(labels)))
+	return append(out, labels...)
+}
+
+// File is a WAV file opened with Create.
+type File struct {
+	*Writer
+	f *os.File
+}
+
+// Create creates a PCM16 WAV file.
+func Create(name string, sampleRate, channels int) (*File, error) {
+	f, err := os.Create(name)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := NewWriter(f, sampleRate, channels)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	return &File{Writer: w, f: f}, nil
+}
+
+// Close finalizes and closes the file.
+func (f *File) Close() error {
+	if err := f.Writer.Close(); err != nil {
+		_ = f.f.Close()
+		return err
+	}
+	return f.f.Close()
+}
@@ -0,0 +1,121 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/stretchr/testify/require"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func samples(values ...int16) []byte {
+	b := make([]byte, len(values)*2)
+	for i, v := range values {
+		binary.LittleEndian.PutUint16(b[i*2:], uint16(v))
+	}
+	return b
+}
+
+func writeFormat(b *bytes.Buffer, tag, channels uint16, rate uint32, bits uint16) {
+	blockAlign := channels * bits / 8
+	for _, v := range []any{uint32(16), tag, channels, rate, rate * uint32(blockAlign), blockAlign, bits} {
+		_ = binary.Write(b, binary.LittleEndian, v)
+	}
+}
+
+func TestWriterReader(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "out.wav")
+
+	f, err := Create(name, 16_000, 1)
+	require.NoError(t, err)
+	_, err = f.Write(samples(1, 2, 3))
+	require.NoError(t, err)
+	_, err = f.Write(samples(-4))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	data, err := os.ReadFile(name)
+	require.NoError(t, err)
+	require.Len(t, data, headerSize+8)
+	require.Equal(t, uint32(36+8), binary.LittleEndian.Uint32(data[4:]))
+
+	r, err := NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, 16_000, r.SampleRate)
+	require.Equal(t, 1, r.Channels)
+
+	pcm, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, samples(1, 2, 3, -4), pcm)
+}
+
+func TestReaderStereo(t *testing.T) {
+	pcm := samples(10, 20, -10, -30, 7, 7)
+
+	var b bytes.Buffer
+	b.WriteString("RIFF")
+	_ = binary.Write(&b, binary.LittleEndian, uint32(0))
+	b.WriteString("WAVE")
+	// an unknown chunk of odd size before the format
+	b.WriteString("LIST")
+	_ = binary.Write(&b, binary.LittleEndian, uint32(3))
+	b.Write([]byte{1, 2, 3, 0})
+	b.WriteString("fmt ")
+	writeFormat(&b, 1, 2, 44_100, 16)
+	b.WriteString("data")
+	_ = binary.Write(&b, binary.LittleEndian, uint32(len(pcm)))
+	b.Write(pcm)
+	// trailing chunk must not be read as audio
+	b.WriteString("junk")
+
+	r, err := NewReader(&b)
+	require.NoError(t, err)
+	require.Equal(t, 44_100, r.SampleRate)
+	require.Equal(t, 2, r.Channels)
+
+	mono, err := io.ReadAll(r.Mono())
+	require.NoError(t, err)
+	require.Equal(t, samples(15, -20, 7), mono)
+}
+
+func TestReaderInvalid(t *testing.T) {
+	_, err := NewReader(bytes.NewReader([]byte("RIFF\x00\x00\x00\x00AVI ")))
+	require.Error(t, err)
+
+	var b bytes.Buffer
+	b.WriteString("RIFF\x00\x00\x00\x00WAVEfmt ")
+	writeFormat(&b, 3, 1, 8_000, 32)
+	_, err = NewReader(&b)
+	require.ErrorContains(t, err, "unsupported wav format")
+}
+
+func TestWriterCues(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "out.wav")
+
+	f, err := Create(name, 8_000, 2)
+	require.NoError(t, err)
+	_, err = f.Write(samples(1, -1, 2, -2))
+	require.NoError(t, err)
+	f.AddCue(1, "agent")
+	require.NoError(t, f.Close())
+
+	data, err := os.ReadFile(name)
+	require.NoError(t, err)
+	require.Equal(t, uint32(len(data)-8), binary.LittleEndian.Uint32(data[4:]))
+
+	cue := bytes.Index(data, []byte("cue "))
+	require.Equal(t, headerSize+8, cue)
+	require.Equal(t, uint32(1), binary.LittleEndian.Uint32(data[cue+8:]))
+	require.Equal(t, uint32(1), binary.LittleEndian.Uint32(data[cue+16:]))
+	require.Contains(t, string(data[cue:]), "labl")
+	require.Contains(t, string(data[cue:]), "agent\x00")
+
+	r, err := NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, 2, r.Channels)
+	pcm, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, samples(1, -1, 2, -2), pcm)
+}
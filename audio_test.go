@@ -0,0 +1,96 @@
+package openairt
+
+import (
+	"context"
+	"encoding/base64"
+	"github.com/codewandler/openairt-go/audio/g711"
+	"github.com/codewandler/openairt-go/events"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestAudioSampleRate(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, srv := openTestClient(t, WithSampleRate(8_000))
+
+	// 100ms of user audio at 8 kHz arrives at the API as 24 kHz
+	_, err := client.Audio().Write(sine(440, 8_000, 800, 0.5))
+	require.NoError(t, err)
+
+	var sent int
+	for sent < 4800-200 {
+		evt, err := srv.Next(ctx, "input_audio_buffer.append")
+		require.NoError(t, err)
+
+		var msg struct {
+			Audio string `json:"audio"`
+		}
+		require.NoError(t, evt.Decode(&msg))
+		data, err := base64.StdEncoding.DecodeString(msg.Audio)
+		require.NoError(t, err)
+		sent += len(data)
+	}
+	require.LessOrEqual(t, sent, 4800)
+
+	// 100ms of agent audio at 24 kHz is read as 8 kHz
+	require.NoError(t, srv.Send(map[string]any{
+		"type":     "response.audio.delta",
+		"event_id": "evt_1",
+		"delta":    base64.StdEncoding.EncodeToString(sine(440, 24_000, 2400, 0.5)),
+	}))
+
+	buf := make([]byte, 4096)
+	n, err := client.Audio().Read(buf)
+	require.NoError(t, err)
+	require.InDelta(t, 1600, n, 64)
+}
+
+func TestAudioG711(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, srv := openTestClient(t, WithAudioFormat(events.AudioFormatG711ULaw))
+
+	evt, err := srv.Next(ctx, "session.update")
+	require.NoError(t, err)
+	var update events.SessionUpdateEvent
+	require.NoError(t, evt.Decode(&update))
+	require.Equal(t, events.AudioFormatG711ULaw, update.Session.InputAudioFormat)
+	require.Equal(t, events.AudioFormatG711ULaw, update.Session.OutputAudioFormat)
+
+	// G.711 is passed through in 20ms chunks of 160 bytes
+	in := g711.EncodeULaw(sine(440, 8_000, 320, 0.5))
+	_, err = client.Audio().Write(in)
+	require.NoError(t, err)
+
+	var sent []byte
+	for len(sent) < len(in) {
+		evt, err := srv.Next(ctx, "input_audio_buffer.append")
+		require.NoError(t, err)
+
+		var msg struct {
+			Audio string `json:"audio"`
+		}
+		require.NoError(t, evt.Decode(&msg))
+		data, err := base64.StdEncoding.DecodeString(msg.Audio)
+		require.NoError(t, err)
+		require.Len(t, data, 160)
+		sent = append(sent, data...)
+	}
+	require.Equal(t, in, sent)
+
+	out := g711.EncodeULaw(sine(440, 8_000, 160, 0.5))
+	require.NoError(t, srv.Send(map[string]any{
+		"type":     "response.audio.delta",
+		"event_id": "evt_1",
+		"delta":    base64.StdEncoding.EncodeToString(out),
+	}))
+
+	buf := make([]byte, 4096)
+	n, err := client.Audio().Read(buf)
+	require.NoError(t, err)
+	require.Equal(t, out, buf[:n])
+}
@@ -2,30 +2,57 @@ package openairt
 
 import (
 	"context"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"github.com/codewandler/openairt-go/events"
 	"github.com/codewandler/openairt-go/internal/websocket"
 	"github.com/codewandler/openairt-go/tool"
+	"github.com/codewandler/openairt-go/wirelog"
 	nanoid "github.com/matoous/go-nanoid/v2"
 	"github.com/smallnest/ringbuffer"
 	"io"
 	"log/slog"
 	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type Client struct {
+	ctx          context.Context
 	config       *clientConfig
 	ws           *websocket.Client
 	onEvent      func(e any)
 	onError      func(e *events.ErrorEvent)
 	onToolCall   func(name string, args map[string]any) (any, error)
-	logger       *slog.Logger
+	onMCPApprove func(req events.ResponseDoneOutput)
+	onDisconnect atomic.Pointer[func(err *DisconnectError)]
+	logger       atomic.Pointer[slog.Logger]
 	update       chan struct{}
-	audioToAgent *ringbuffer.RingBuffer
+	audioToAgent *inputBuffer
 	audioToUser  *ringbuffer.RingBuffer
+	// convert between the configured sample rate and the one of the API
+	inputResampler  *Resampler
+	outputMu        sync.Mutex // local barge-in resets outputResampler off the read loop
+	outputResampler *Resampler
+	playout         *playout
+	usage           *usageTracker
+	telemetry       *telemetry
+	turns           *turnTracker
+	rateLimitsMu    sync.Mutex
+	rateLimits      []events.RateLimit
+	streams         *outputStreams
+	interrupts      atomic.Int64 // interruptions that dropped output audio
+	outputSeen      atomic.Int64 // interruptions reported by OutputAudio
+	vad             *localVAD
+	activeMu        sync.Mutex
+	active          map[string]bool // responses in progress
+	holdingsMu      sync.Mutex
+	holdings        map[string]*holding
+	pendingMu       sync.Mutex
+	pending         map[string]*pendingCall
+	userItems       int64 // user messages so far, guarded by pendingMu
 }
 
 type readWriter struct {
@@ -33,12 +60,37 @@ type readWriter struct {
 	io.Writer
 }
 
-// Audio returns user audio
-func (c *Client) Audio() io.ReadWriter {
-	return &readWriter{
-		Reader: c.audioToUser,
-		Writer: c.audioToAgent,
+// InputStats returns the counters of the input audio buffer.
+func (c *Client) InputStats() InputStats {
+	return c.audioToAgent.stats()
+}
+
+// OutputStreams returns the audio of each response item as its own stream.
+// It requires WithOutputStreams and returns nil before Open. The channel is
+// closed by Close. Streams of items are dropped, with a warning, while 64
+// streams wait to be received.
+func (c *Client) OutputStreams() <-chan *OutputStream {
+	if c.streams == nil {
+		return nil
+	}
+	return c.streams.ch
+}
+
+// Playhead returns the position of the response audio released to Audio().
+// It requires WithPlayout.
+func (c *Client) Playhead() Playhead {
+	if c.playout == nil {
+		return Playhead{}
+	}
+	return c.playout.position()
+}
+
+// FlushPlayout drops all response audio not read from Audio() yet.
+func (c *Client) FlushPlayout() {
+	if c.playout != nil {
+		c.playout.flush()
 	}
+	c.resetOutput()
 }
 
 func (c *Client) OnEvent(h func(e any)) {
@@ -53,19 +105,30 @@ func (c *Client) OnToolCall(h func(name string, args map[string]any) (any, error
 	c.onToolCall = h
 }
 
-// Send sends any kind of event to the websocket
+// Send sends any kind of event to the websocket. It fails with
+// ErrNotConnected before Open and with an error wrapping a *DisconnectError
+// once the connection was lost.
 func (c *Client) Send(evt any) error {
 	data, err := json.Marshal(evt)
 	if err != nil {
 		return err
 	}
 
-	c.ws.WriteText(data)
-
-	return nil
+	if c.ws == nil {
+		return ErrNotConnected
+	}
+	return c.ws.WriteText(data)
 }
 
 func (c *Client) CreateResponseWithPayload(p events.ResponseCreatePayload) error {
+	if err := c.beforeResponse(); err != nil {
+		return err
+	}
+
+	return c.sendResponseCreate(p)
+}
+
+func (c *Client) sendResponseCreate(p events.ResponseCreatePayload) error {
 	return c.Send(events.ResponseCreateEvent{
 		BaseEvent: events.NewBaseEvent("response.create"),
 		Response:  p,
@@ -73,21 +136,18 @@ func (c *Client) CreateResponseWithPayload(p events.ResponseCreatePayload) error
 }
 
 func (c *Client) CreateResponse() error {
-	return c.Send(events.ResponseCreateEvent{
-		BaseEvent: events.NewBaseEvent("response.create"),
-		Response:  events.ResponseCreatePayload{},
-	})
+	return c.CreateResponseWithPayload(events.ResponseCreatePayload{})
 }
 
-func dispatchEvent[T any](f func(any), data []byte) {
+func dispatchEvent[T any](c *Client, data []byte) {
 	evt, err := events.Parse[T](data)
 	if err != nil {
-		slog.Error("failed to parse event", slog.Any("err", err))
+		c.log().Error("failed to parse event", slog.Any("err", err))
 		return
 	}
 
-	if f != nil {
-		f(evt)
+	if c.onEvent != nil {
+		c.onEvent(evt)
 	}
 }
 
@@ -114,6 +174,14 @@ func (c *Client) SessionUpdate(session events.SessionUpdate) error {
 }
 
 func (c *Client) UserInput(text string, respond bool) (err error) {
+	// check before the item is added, so a refused response leaves no
+	// unanswered input
+	if respond {
+		if err := c.beforeResponse(); err != nil {
+			return err
+		}
+	}
+
 	id, _ := nanoid.New()
 	err = c.Send(events.ConversationItemCreateEvent{
 		BaseEvent: events.NewBaseEvent("conversation.item.create"),
@@ -131,29 +199,152 @@ func (c *Client) UserInput(text string, respond bool) (err error) {
 	}
 
 	if respond {
-		return c.CreateResponse()
+		return c.sendResponseCreate(events.ResponseCreatePayload{})
 	}
 
 	return nil
 
 }
 
+// CommitInput commits the input audio buffer as a user message. It is only
+// needed without turn detection of the server, see VADConfig.TurnDetection.
+func (c *Client) CommitInput() error {
+	return c.Send(events.InputAudioBufferCommitEvent{
+		BaseEvent: events.NewBaseEvent("input_audio_buffer.commit"),
+	})
+}
+
+// interruptOutput drops the response audio not played yet, the user started
+// speaking.
+func (c *Client) interruptOutput(userItemID string) {
+	if c.playout != nil {
+		if c.playout.interrupt() {
+			// the reader learns about it like without playout, the fade
+			// follows
+			c.interrupts.Add(1)
+			c.audioToUser.Reset()
+		}
+	} else if c.streams != nil {
+		c.streams.interrupt()
+	} else {
+		c.resetOutput()
+	}
+	if c.outputResampler != nil {
+		c.outputMu.Lock()
+		c.outputResampler.Reset()
+		c.outputMu.Unlock()
+	}
+
+	if c.config.recorder != nil {
+		c.config.recorder.interrupt(userItemID)
+	}
+}
+
+// cancelActiveResponses cancels all responses in progress.
+func (c *Client) cancelActiveResponses() {
+	c.activeMu.Lock()
+	ids := make([]string, 0, len(c.active))
+	for id := range c.active {
+		ids = append(ids, id)
+	}
+	c.activeMu.Unlock()
+
+	for _, id := range ids {
+		c.cancelResponse(id)
+	}
+}
+
 func (c *Client) Open(ctx context.Context) error {
 	if err := c.config.validate(); err != nil {
 		return fmt.Errorf("invalid config: %w", err)
 	}
 
+	c.ctx = ctx
+	c.telemetry.startSession(ctx, c.config.model)
+
+	if c.config.inFormat == events.AudioFormatPCM16 {
+		r, err := NewResampler(c.config.sampleRate, apiSampleRate)
+		if err != nil {
+			return err
+		}
+		c.inputResampler = r
+	}
+	if c.config.outFormat == events.AudioFormatPCM16 {
+		r, err := NewResampler(apiSampleRate, c.config.sampleRate)
+		if err != nil {
+			return err
+		}
+		c.outputResampler = r
+	}
+
+	if c.config.recorder != nil {
+		if err := c.config.recorder.begin(c.config); err != nil {
+			return err
+		}
+	}
+
+	if c.config.playout != nil {
+		var onRelease func(string, int)
+		if c.config.recorder != nil {
+			onRelease = c.config.recorder.agentItem
+		}
+
+		rate, sampleSize := c.config.audioRate(c.config.outFormat)
+		c.playout = newPlayout(*c.config.playout, c.config.outFormat, rate, sampleSize, c.audioToUser, onRelease)
+		go c.playout.run(ctx)
+	}
+
+	if c.config.streams {
+		c.streams = newOutputStreams(c.config.recorder, c.log)
+	}
+
+	if c.config.vad != nil {
+		c.vad = newLocalVAD(c.config)
+	}
+
 	headers := http.Header{}
 	headers.Add("Authorization", fmt.Sprintf("Bearer %s", c.config.apiKey))
 	headers.Add("OpenAI-Beta", "realtime=v1")
 
-	initialized := make(chan error)
+	// writes fail again once this connection ended
+	input := c.audioToAgent.reopen()
+
+	// buffered, so the session.created handler does not block if Open gave up
+	initialized := make(chan error, 1)
+	connected := make(chan struct{})
+
+	onDisconnect := func(err error) {
+		c.handleDisconnect(input, err)
+	}
+
+	var onWrite func([]byte)
+	if wire := c.config.wire; wire != nil {
+		onWrite = func(data []byte) {
+			wire.Record(wirelog.Out, data)
+		}
+	}
 
 	if ws, err := websocket.Connect(ctx, websocket.ClientConfig{
-		Logger:  slog.New(slog.DiscardHandler),
-		URL:     fmt.Sprintf("wss://api.openai.com/v1/realtime?model=%s", c.config.model),
-		Headers: headers,
+		Logger:         slog.New(&logHandler{c: c}),
+		OnWrite:        onWrite,
+		WriteQueue:     writeQueue,
+		URL:            fmt.Sprintf("%s?model=%s", c.config.baseURL, c.config.model),
+		Headers:        headers,
+		PingInterval:   c.config.keepalive.PingInterval,
+		PongTimeout:    c.config.keepalive.PongTimeout,
+		IdleTimeout:    c.config.keepalive.IdleTimeout,
+		WriteTimeout:   c.config.keepalive.WriteTimeout,
+		OnDisconnect:   onDisconnect,
+		NetDial:        c.config.netDial,
+		Proxy:          c.config.proxy,
+		TLSConfig:      c.config.tlsConfig,
+		ProxyTLSConfig: c.config.proxyTLSConfig,
+		Compression:    c.config.compression,
 		OnText: func(data []byte) error {
+			if c.config.wire != nil {
+				c.config.wire.Record(wirelog.In, data)
+			}
+
 			var x struct {
 				Type    string `json:"type"`
 				EventID string `json:"event_id"`
@@ -167,120 +358,163 @@ func (c *Client) Open(ctx context.Context) error {
 			case "error":
 				evt, err := events.Parse[events.ErrorEvent](data)
 				if err != nil {
-					slog.Error("failed to parse error event", slog.Any("err", err))
-				} else if c.onError != nil {
-					c.onError(evt)
+					c.log().Error("failed to parse error event", slog.Any("err", err))
+				} else {
+					c.log().Warn("server error",
+						slog.String("code", evt.ErrorDetail.Code),
+						slog.String("event_id", evt.ErrorDetail.EventID),
+						slog.String("message", evt.ErrorDetail.Message))
+					if c.onError != nil {
+						c.onError(evt)
+					}
 				}
 			// TODO: case "response.done":
 			// TODO: case "input_audio_buffer.speech_started":
 			// TODO: case "input_audio_buffer.speech_stopped":
 			case "session.created":
-				dispatchEvent[events.SessionCreatedEvent](c.onEvent, data)
+				if evt, err := events.Parse[events.SessionCreatedEvent](data); err == nil {
+					c.setSessionID(evt.Session.ID)
+				}
+				dispatchEvent[events.SessionCreatedEvent](c, data)
 				go func() {
+					// session.created may arrive before Connect returned
+					<-connected
+
+					tools := c.config.tools
+					if c.config.approver != nil && len(tools) > 0 {
+						tools = append(tools[:len(tools):len(tools)], confirmTool)
+					}
+
+					turnDetection := &events.TurnDetection{
+						CreateResponse:    true,
+						InterruptResponse: true,
+						Type:              "server_vad",
+						//SilenceDurationMs: 500,
+						//PrefixPaddingMs:   100,
+						//Threshold:         0.9,
+					}
+					if c.vad != nil && c.vad.config.TurnDetection {
+						// sent as null
+						turnDetection = &events.TurnDetection{}
+					}
 
 					toolChoice := tool.ChoiceNone
-					if len(c.config.tools) > 0 {
+					if len(tools) > 0 {
 						toolChoice = tool.ChoiceAuto
 					}
 
 					initialized <- c.SessionUpdate(events.SessionUpdate{
 						Voice:             c.config.voice,
-						InputAudioFormat:  events.AudioFormatPCM16,
-						OutputAudioFormat: events.AudioFormatPCM16,
+						InputAudioFormat:  c.config.inFormat,
+						OutputAudioFormat: c.config.outFormat,
 						Temperature:       c.config.temperature,
 						Speed:             c.config.speed,
 						Instructions:      c.config.instruction,
 						Modalities:        []string{"text", "audio"},
 						ToolChoice:        toolChoice,
-						Tools:             c.config.tools,
-						TurnDetection: &events.TurnDetection{
-							CreateResponse:    true,
-							InterruptResponse: true,
-							Type:              "server_vad",
-							//SilenceDurationMs: 500,
-							//PrefixPaddingMs:   100,
-							//Threshold:         0.9,
-						},
+						Tools:             tools,
+						TurnDetection:     turnDetection,
 					})
 				}()
+			case "rate_limits.updated":
+				evt, err := events.Parse[events.RateLimitsUpdatedEvent](data)
+				if err != nil {
+					c.log().Error("failed to parse rate limits updated event", slog.Any("err", err))
+				} else {
+					c.handleRateLimits(evt)
+				}
+
+				dispatchEvent[events.RateLimitsUpdatedEvent](c, data)
 			case "session.updated":
 				c.update <- struct{}{}
-				dispatchEvent[events.SessionUpdateEvent](c.onEvent, data)
-			case "response.done":
-				evt, err := events.Parse[events.ResponseDoneEvent](data)
+				dispatchEvent[events.SessionUpdateEvent](c, data)
+			case "response.created":
+				evt, err := events.Parse[events.ResponseCreatedEvent](data)
 				if err != nil {
-					slog.Error("failed to parse response done event", slog.Any("err", err))
+					c.log().Error("failed to parse response created event", slog.Any("err", err))
+				} else {
+					c.activeMu.Lock()
+					c.active[evt.Response.ID] = true
+					c.activeMu.Unlock()
+					c.log().Debug("response created", slog.String("response_id", evt.Response.ID))
+					c.telemetry.responseCreated(evt.Response.ID)
+					_, filler := evt.Response.MetaData[holdingMetadataKey]
+					c.turns.responseCreated(evt.Response.ID, filler)
+
+					// responses of the server turn detection bypass
+					// CreateResponse
+					if c.usage.overBudget() {
+						c.cancelResponse(evt.Response.ID)
+					}
+
+					c.handleResponseCreated(evt)
 				}
 
-				if c.onToolCall != nil {
-					for _, o := range evt.Response.Output {
-						if o.Type == "function_call" && o.Status == "completed" {
-							var args map[string]any
-							if err := json.Unmarshal([]byte(o.Arguments), &args); err != nil {
-								return err
-							}
-
-							res, err := c.onToolCall(o.Name, args)
-							c.logger.Debug("tool call", slog.Any("name", o.Name), slog.Any("args", args), slog.Any("res", res), slog.Any("err", err))
-							// TODO: add to conversation
-
-							var toolOutput = func() string {
-								if err != nil {
-									d, _ := json.Marshal(map[string]any{
-										"error": err.Error(),
-									})
-									return string(d)
-								} else if res != nil {
-									d, _ := json.Marshal(res)
-									return string(d)
-								} else {
-									d, _ := json.Marshal(map[string]any{
-										"success": true,
-									})
-									return string(d)
-								}
-							}()
-
-							_ = c.Send(events.ConversationItemCreateEvent{
-								BaseEvent: events.NewBaseEvent("conversation.item.create"),
-								Item: events.ConversationItem{
-									ID:     o.CallID,
-									Type:   "function_call_output",
-									CallID: o.CallID,
-									Output: toolOutput,
-								},
-							})
-							_ = c.CreateResponse()
+				dispatchEvent[events.ResponseCreatedEvent](c, data)
+			case "response.done":
+				evt, err := events.Parse[events.ResponseDoneEvent](data)
+				if err != nil {
+					c.log().Error("failed to parse response done event", slog.Any("err", err))
+				} else {
+					c.activeMu.Lock()
+					delete(c.active, evt.Response.ID)
+					c.activeMu.Unlock()
+					c.log().Debug("response done", slog.String("response_id", evt.Response.ID), slog.String("status", evt.Response.Status))
+
+					if c.playout != nil || c.streams != nil {
+						itemIDs := make([]string, 0, len(evt.Response.Output))
+						for _, o := range evt.Response.Output {
+							itemIDs = append(itemIDs, o.ID)
+						}
+						if c.playout != nil {
+							c.playout.responseDone(itemIDs)
+						} else {
+							c.streams.responseDone(itemIDs)
 						}
 					}
+
+					c.handleUsage(evt.Response)
+					c.telemetry.responseDone(evt.Response)
+					toolCalls := slices.ContainsFunc(evt.Response.Output, func(o events.ResponseDoneOutput) bool {
+						return o.Type == "function_call"
+					})
+					c.turns.responseDone(evt.Response.ID, toolCalls)
+					c.handleResponseDone(evt)
 				}
 
 				// dispatch
-				dispatchEvent[events.ResponseDoneEvent](c.onEvent, data)
+				dispatchEvent[events.ResponseDoneEvent](c, data)
 
 			case "response.audio_transcript.done":
-				dispatchEvent[events.ResponseAudioTranscriptDoneEvent](c.onEvent, data)
+				dispatchEvent[events.ResponseAudioTranscriptDoneEvent](c, data)
 			case "response.audio_transcript.delta":
-				dispatchEvent[events.ResponseAudioTranscriptDeltaEvent](c.onEvent, data)
+				if evt, err := events.Parse[events.ResponseAudioTranscriptDeltaEvent](data); err == nil {
+					c.turns.transcriptDelta(evt.ResponseId)
+				}
+				dispatchEvent[events.ResponseAudioTranscriptDeltaEvent](c, data)
 			case "response.audio.done":
-				dispatchEvent[events.ResponseAudioDone](c.onEvent, data)
+				if c.playout != nil || c.streams != nil {
+					evt, err := events.Parse[events.ResponseAudioDone](data)
+					if err != nil {
+						c.log().Error("failed to parse response audio done event", slog.Any("err", err))
+					} else if c.playout != nil {
+						c.playout.audioDone(evt.ItemID)
+					} else {
+						c.streams.audioDone(evt.ItemID)
+					}
+				}
+
+				dispatchEvent[events.ResponseAudioDone](c, data)
 			case "response.audio.delta":
 
 				evt, err := events.Parse[events.ResponseAudioDeltaEvent](data)
 				if err != nil {
-					slog.Error("failed to parse response audio delta event", slog.Any("err", err))
+					c.log().Error("failed to parse response audio delta event", slog.Any("err", err))
 				} else {
-					data, err := base64.StdEncoding.DecodeString(evt.Delta)
-					if err != nil {
-						slog.Error("failed to decode base64 data", slog.Any("err", err))
-					}
-					if _, err = c.audioToUser.Write(data); err != nil {
-						c.logger.Error("failed to write to audio read buffer", slog.Any("err", err))
-					}
+					c.handleAudioDelta(evt)
 				}
 
 			case "input_audio_buffer.speech_started":
-				println("-- STARTED --")
 				/*id1, _ := nanoid.New()
 				c.Send(map[string]any{
 					"event_id": id1,
@@ -292,57 +526,69 @@ func (c *Client) Open(ctx context.Context) error {
 					"type":     "input_audio_buffer.clear",
 				})*/
 
-				c.audioToUser.Reset()
+				evt, err := events.Parse[events.SpeechStartedEvent](data)
+				if err != nil {
+					c.log().Error("failed to parse speech started event", slog.Any("err", err))
+				} else {
+					c.interruptOutput(evt.ItemID)
+				}
 
-				dispatchEvent[events.SpeechStartedEvent](c.onEvent, data)
+				dispatchEvent[events.SpeechStartedEvent](c, data)
 			case "input_audio_buffer.speech_stopped":
-				dispatchEvent[events.SpeechStoppedEvent](c.onEvent, data)
+				c.telemetry.speechStopped()
+				if evt, err := events.Parse[events.SpeechStoppedEvent](data); err == nil {
+					c.turns.speechStopped(evt.ItemID)
+				}
+				dispatchEvent[events.SpeechStoppedEvent](c, data)
+			case "input_audio_buffer.committed":
+				if evt, err := events.Parse[events.InputAudioBufferCommittedEvent](data); err == nil {
+					c.turns.committed(evt.ItemID)
+				}
+				c.userItem()
+				dispatchEvent[events.InputAudioBufferCommittedEvent](c, data)
+			case "conversation.item.created":
+				if evt, err := events.Parse[events.ConversationItemCreatedEvent](data); err == nil && evt.Item.Role == "user" {
+					c.userItem()
+				}
+				dispatchEvent[events.ConversationItemCreatedEvent](c, data)
 			}
 
 			return nil
 		},
 	}); err != nil {
+		c.closeInput(input, err)
 		return err
 	} else {
 		c.ws = ws
+		close(connected)
 	}
 
-	go func() {
-		buf := make([]byte, 960)
-
-		for {
-			n, err := c.audioToAgent.Read(buf)
-			if err != nil {
-				if err == io.EOF {
-					return
-				}
-				if err.Error() == "reset called" {
-					continue
-				}
+	go c.pumpInput(c.ws, input)
 
-				c.logger.Error("failed to read from agent audio buffer", slog.Any("err", err))
-				return
-			}
-
-			data := buf[:n]
-			id, _ := nanoid.New()
-
-			evtData, err := json.Marshal(map[string]any{
-				"event_id": id,
-				"type":     "input_audio_buffer.append",
-				"audio":    base64.StdEncoding.EncodeToString(data),
-			})
-			if err != nil {
-				c.logger.Error("failed to marshal input audio buffer append event", slog.Any("err", err))
-				return
-			}
+	select {
+	case err := <-initialized:
+		return err
+	case <-c.ws.Done():
+		return c.ws.Err()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 
-			c.ws.WriteText(evtData)
-		}
-	}()
+}
 
-	return <-initialized
+// Close ends the input audio and the telemetry session and closes the
+// connection.
+func (c *Client) Close(ctx context.Context) error {
+	_ = c.audioToAgent.Close()
+	defer c.telemetry.endSession()
+	if c.streams != nil {
+		defer c.streams.close()
+	}
 
+	if c.ws == nil {
+		return nil
+	}
+	return c.ws.Close(ctx)
 }
 
 func New(opts ...ClientOption) *Client {
@@ -350,16 +596,32 @@ func New(opts ...ClientOption) *Client {
 	withDefaults()(config)
 	WithOptions(opts...)(config)
 
-	audioToAgent := ringbuffer.New(24_000 * 2 * 1).SetBlocking(true)
-	audioToUser := ringbuffer.New(24_000 * 2 * 60).SetBlocking(true)
+	inRate, inSampleSize := config.audioRate(config.inFormat)
+	outRate, outSampleSize := config.audioRate(config.outFormat)
 
-	return &Client{
+	// the buffer holds at least one chunk, so the input pump gets to read
+	bufferSize := max(config.inputBuffer, config.inputChunk)
+	audioToAgent := newInputBuffer(int(float64(inRate)*bufferSize.Seconds())*inSampleSize, inSampleSize, config.overflow)
+	t := newTelemetry(config)
+	audioToAgent.onDrop = t.audioDropped
+
+	audioToUser := ringbuffer.New(outRate * outSampleSize * 60).SetBlocking(true)
+
+	c := &Client{
 		config:       config,
-		logger:       slog.Default(),
 		update:       make(chan struct{}, 1),
 		audioToAgent: audioToAgent,
 		audioToUser:  audioToUser,
+		usage:        newUsageTracker(config),
+		telemetry:    t,
+		turns:        newTurnTracker(config.turnWindow),
+		holdings:     map[string]*holding{},
+		pending:      map[string]*pendingCall{},
+		active:       map[string]bool{},
 	}
+	c.logger.Store(config.logger)
+
+	return c
 }
 
 type InterruptEvent struct {
@@ -0,0 +1,15 @@
+package openairt
+
+import (
+	"github.com/codewandler/openairt-go/internal/websocket"
+)
+
+// CompressionConfig configures the permessage-deflate compression of the
+// connection, see WithCompression.
+type CompressionConfig = websocket.CompressionConfig
+
+// Compressed reports whether the server accepted to compress the messages of
+// the connection, see WithCompression.
+func (c *Client) Compressed() bool {
+	return c.ws != nil && c.ws.Compressed()
+}
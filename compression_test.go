@@ -0,0 +1,20 @@
+package openairt
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestCompressionDeclined(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// the mock server does not support permessage-deflate
+	client, srv := openTestClient(t, WithCompression(CompressionConfig{}))
+	require.False(t, client.Compressed())
+
+	_, err := srv.Next(ctx, "session.update")
+	require.NoError(t, err)
+}
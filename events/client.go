@@ -5,6 +5,18 @@ type SessionUpdateEvent struct {
 	Session SessionUpdate `json:"session"`
 }
 
+// ResponseCancelEvent cancels an in-progress response.
+type ResponseCancelEvent struct {
+	BaseEvent
+	ResponseID string `json:"response_id,omitempty"`
+}
+
+// InputAudioBufferCommitEvent commits the input audio buffer as a user
+// message, needed without turn detection of the server.
+type InputAudioBufferCommitEvent struct {
+	BaseEvent
+}
+
 type ConversationItemCreateEvent struct {
 	BaseEvent
 	Item ConversationItem `json:"item"`
@@ -18,6 +30,11 @@ type ConversationItem struct {
 	Content []ConversationItemContent `json:"content,omitempty"`
 	CallID  string                    `json:"call_id,omitempty"`
 	Output  string                    `json:"output,omitempty"`
+
+	// mcp_approval_response
+	ApprovalRequestID string `json:"approval_request_id,omitempty"`
+	Approve           *bool  `json:"approve,omitempty"`
+	Reason            string `json:"reason,omitempty"`
 }
 
 type ConversationItemContent struct {
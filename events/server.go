@@ -1,6 +1,7 @@
 package events
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/codewandler/openairt-go/tool"
 )
@@ -8,9 +9,31 @@ import (
 type AudioFormat string
 
 const (
-	AudioFormatPCM16 AudioFormat = "pcm16"
+	AudioFormatPCM16    AudioFormat = "pcm16"
+	AudioFormatG711ULaw AudioFormat = "g711_ulaw"
+	AudioFormatG711ALaw AudioFormat = "g711_alaw"
 )
 
+// SampleRate returns the sample rate the API uses for the format.
+func (f AudioFormat) SampleRate() int {
+	switch f {
+	case AudioFormatG711ULaw, AudioFormatG711ALaw:
+		return 8_000
+	default:
+		return 24_000
+	}
+}
+
+// BytesPerSample returns the size of a single mono sample.
+func (f AudioFormat) BytesPerSample() int {
+	switch f {
+	case AudioFormatG711ULaw, AudioFormatG711ALaw:
+		return 1
+	default:
+		return 2
+	}
+}
+
 type ErrorEvent struct {
 	BaseEvent
 	ErrorDetail ErrorDetail `json:"error"`
@@ -58,12 +81,37 @@ type ResponseCreatePayload struct {
 	MaxOutputTokens   int            `json:"max_output_tokens,omitempty"`
 }
 
+type ResponseCreatedEvent struct {
+	BaseEvent
+	Response ResponseDoneResponse `json:"response"`
+}
+
 type SpeechStartedEvent struct {
 	BaseEvent
+	AudioStartMs int    `json:"audio_start_ms"`
+	ItemID       string `json:"item_id"`
 }
 
 type SpeechStoppedEvent struct {
 	BaseEvent
+	AudioEndMs int    `json:"audio_end_ms"`
+	ItemID     string `json:"item_id"`
+}
+
+// InputAudioBufferCommittedEvent reports the user message created from the
+// input audio buffer.
+type InputAudioBufferCommittedEvent struct {
+	BaseEvent
+	PreviousItemID string `json:"previous_item_id"`
+	ItemID         string `json:"item_id"`
+}
+
+// ConversationItemCreatedEvent reports an item added to the conversation,
+// e.g. a user message.
+type ConversationItemCreatedEvent struct {
+	BaseEvent
+	PreviousItemID string           `json:"previous_item_id"`
+	Item           ConversationItem `json:"item"`
 }
 
 type ResponseAudioDeltaEvent struct {
@@ -108,13 +156,64 @@ type ResponseDoneEvent struct {
 }
 
 type ResponseDoneResponse struct {
-	Object   string               `json:"object"`
-	ID       string               `json:"id"`
-	Status   string               `json:"status"`
-	Output   []ResponseDoneOutput `json:"output"`
-	MetaData map[string]any       `json:"metadata"`
+	Object         string               `json:"object"`
+	ID             string               `json:"id"`
+	ConversationID string               `json:"conversation_id,omitempty"`
+	Status         string               `json:"status"`
+	StatusDetails  *StatusDetails       `json:"status_details,omitempty"`
+	Output         []ResponseDoneOutput `json:"output"`
+	MetaData       map[string]any       `json:"metadata"`
+	Usage          *Usage               `json:"usage,omitempty"`
+}
+
+// StatusDetails explains why a response is not completed.
+type StatusDetails struct {
+	// Type is the status: cancelled, incomplete or failed.
+	Type string `json:"type"`
+	// Reason is turn_detected or client_cancelled for cancelled responses,
+	// max_output_tokens or content_filter for incomplete ones.
+	Reason string `json:"reason,omitempty"`
+	// Error is set for failed responses.
+	Error *StatusError `json:"error,omitempty"`
+}
+
+// StatusError is the error of a failed response.
+type StatusError struct {
+	Type string `json:"type"`
+	Code string `json:"code,omitempty"`
+}
+
+// Usage is the token usage of a response.
+type Usage struct {
+	TotalTokens        int                `json:"total_tokens"`
+	InputTokens        int                `json:"input_tokens"`
+	OutputTokens       int                `json:"output_tokens"`
+	InputTokenDetails  InputTokenDetails  `json:"input_token_details"`
+	OutputTokenDetails OutputTokenDetails `json:"output_token_details"`
+}
+
+// InputTokenDetails splits the input tokens by modality. The cached tokens
+// are included in the text and audio tokens.
+type InputTokenDetails struct {
+	CachedTokens        int                 `json:"cached_tokens"`
+	TextTokens          int                 `json:"text_tokens"`
+	AudioTokens         int                 `json:"audio_tokens"`
+	CachedTokensDetails CachedTokensDetails `json:"cached_tokens_details"`
 }
 
+type CachedTokensDetails struct {
+	TextTokens  int `json:"text_tokens"`
+	AudioTokens int `json:"audio_tokens"`
+}
+
+type OutputTokenDetails struct {
+	TextTokens  int `json:"text_tokens"`
+	AudioTokens int `json:"audio_tokens"`
+}
+
+// ResponseDoneOutput is an output item of a response. Besides function calls
+// it holds the items of remote MCP tools: mcp_list_tools, mcp_call and
+// mcp_approval_request.
 type ResponseDoneOutput struct {
 	Object    string `json:"object"`
 	ID        string `json:"id"`
@@ -123,4 +222,59 @@ type ResponseDoneOutput struct {
 	Name      string `json:"name"`
 	CallID    string `json:"call_id"`
 	Arguments string `json:"arguments"`
+
+	ServerLabel       string    `json:"server_label,omitempty"`
+	Tools             []MCPTool `json:"tools,omitempty"`
+	Output            string    `json:"output,omitempty"`
+	Error             *MCPError `json:"error,omitempty"`
+	ApprovalRequestID string    `json:"approval_request_id,omitempty"`
+}
+
+// MCPTool is a tool listed by a remote MCP server.
+type MCPTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	InputSchema map[string]any `json:"input_schema,omitempty"`
+	Annotations map[string]any `json:"annotations,omitempty"`
+}
+
+// MCPError is the error of a failed mcp_call.
+type MCPError struct {
+	Type    string `json:"type,omitempty"`
+	Code    any    `json:"code,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e *MCPError) UnmarshalJSON(data []byte) error {
+	// some items carry the error as plain string
+	var msg string
+	if err := json.Unmarshal(data, &msg); err == nil {
+		*e = MCPError{Message: msg}
+		return nil
+	}
+
+	type mcpError MCPError
+	return json.Unmarshal(data, (*mcpError)(e))
+}
+
+func (e *MCPError) Error() string {
+	if e.Type == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Type, e.Message)
+}
+
+// RateLimitsUpdatedEvent reports the rate limits after a response was
+// created.
+type RateLimitsUpdatedEvent struct {
+	BaseEvent
+	RateLimits []RateLimit `json:"rate_limits"`
+}
+
+// RateLimit is the state of one limit, requests or tokens.
+type RateLimit struct {
+	Name         string  `json:"name"`
+	Limit        int     `json:"limit"`
+	Remaining    int     `json:"remaining"`
+	ResetSeconds float64 `json:"reset_seconds"`
 }
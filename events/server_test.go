@@ -0,0 +1,18 @@
+package events
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestParseMCPCallError(t *testing.T) {
+	for _, data := range []string{
+		`{"type": "mcp_call", "error": "tool failed"}`,
+		`{"type": "mcp_call", "error": {"type": "tool_execution_error", "message": "tool failed"}}`,
+	} {
+		o, err := Parse[ResponseDoneOutput]([]byte(data))
+		require.NoError(t, err)
+		require.NotNil(t, o.Error)
+		require.Equal(t, "tool failed", o.Error.Message)
+	}
+}
@@ -1,6 +1,9 @@
 package events
 
-import "github.com/codewandler/openairt-go/tool"
+import (
+	"encoding/json"
+	"github.com/codewandler/openairt-go/tool"
+)
 
 type Session struct {
 	ID                       string         `json:"id,omitempty"`
@@ -50,3 +53,14 @@ type TurnDetection struct {
 	CreateResponse    bool    `json:"create_response,omitempty"`
 	InterruptResponse bool    `json:"interrupt_response,omitempty"`
 }
+
+// MarshalJSON encodes an empty TurnDetection as null, which disables the
+// turn detection of the server.
+func (t TurnDetection) MarshalJSON() ([]byte, error) {
+	if t == (TurnDetection{}) {
+		return []byte("null"), nil
+	}
+
+	type plain TurnDetection
+	return json.Marshal(plain(t))
+}
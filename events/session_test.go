@@ -0,0 +1,21 @@
+package events
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestTurnDetectionJSON(t *testing.T) {
+	data, err := json.Marshal(SessionUpdate{TurnDetection: &TurnDetection{}})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"turn_detection":null}`, string(data))
+
+	data, err = json.Marshal(SessionUpdate{TurnDetection: &TurnDetection{Type: "server_vad", CreateResponse: true}})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"turn_detection":{"type":"server_vad","create_response":true}}`, string(data))
+
+	data, err = json.Marshal(SessionUpdate{})
+	require.NoError(t, err)
+	require.JSONEq(t, `{}`, string(data))
+}
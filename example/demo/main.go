@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"github.com/codewandler/audio-go"
@@ -60,6 +61,7 @@ func main() {
 	// openAI client
 	client := openairt.New(
 		openairt.WithDefaultLogger(),
+		openairt.WithSampleRate(sr),
 		openairt.WithInstruction(instruction),
 		openairt.WithTools(
 			tool.Tool{
@@ -137,7 +139,8 @@ func main() {
 		panic(err)
 	}
 
-	audioUser := client.Audio()
+	audioOut := client.OutputAudio()
+	audioIn := client.InputAudio()
 
 	//must(client.UserInput("Hi, my name is timo. Can you ", true))
 	must(client.CreateResponse())
@@ -149,9 +152,9 @@ func main() {
 		bufferSize := 3200
 		buf := make([]byte, bufferSize)
 		for {
-			n, err := audioUser.Read(buf)
+			n, err := audioOut.Read(buf)
 			if err != nil {
-				if err.Error() == "reset called" {
+				if errors.Is(err, openairt.ErrInterrupted) {
 					<-time.After(100 * time.Millisecond)
 					println("-- reset --")
 					continue
@@ -159,13 +162,7 @@ func main() {
 				panic(err)
 			}
 
-			data := buf[:n]
-			resampled, err := openairt.ResamplePCM(data, 24_000, sr)
-			if err != nil {
-				panic(err)
-			}
-
-			n, err = audioDevice.Write(resampled)
+			_, err = audioDevice.Write(buf[:n])
 			if err != nil {
 				panic(err)
 			}
@@ -182,13 +179,7 @@ func main() {
 				panic(err)
 			}
 
-			data := buf[:n]
-			resampled, err := openairt.ResamplePCM(data, sr, 24_000)
-			if err != nil {
-				panic(err)
-			}
-
-			_, err = audioUser.Write(resampled)
+			_, err = audioIn.Write(buf[:n])
 			if err != nil {
 				panic(err)
 			}
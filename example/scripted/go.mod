@@ -0,0 +1,5 @@
+module scripted
+
+go 1.24.3
+
+require github.com/codewandler/openairt-go v0.6.0
@@ -0,0 +1,173 @@
+// Command scripted runs a call from audio files without a microphone. Each
+// input file is one user turn; the assistant audio is recorded to a WAV file.
+//
+//	go run . -in hello.wav -in question.wav -out reply.wav
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/codewandler/openairt-go"
+	"github.com/codewandler/openairt-go/audio/source"
+	"github.com/codewandler/openairt-go/audio/wav"
+	"github.com/codewandler/openairt-go/events"
+	"log"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type files []string
+
+func (f *files) String() string { return strings.Join(*f, ",") }
+
+func (f *files) Set(v string) error {
+	*f = append(*f, v)
+	return nil
+}
+
+func main() {
+	var (
+		inputs      files
+		output      = "reply.wav"
+		sr          = 24_000
+		speed       = 1.0
+		silence     = time.Second
+		timeout     = time.Minute
+		debug       = false
+		instruction = "You are a help-center agent and help the user. You speak english language."
+	)
+
+	flag.Var(&inputs, "in", "WAV or raw PCM16 file of a user turn, repeat for more turns.")
+	flag.StringVar(&output, "out", output, "WAV file to record the agent audio to.")
+	flag.IntVar(&sr, "sample-rate", sr, "sample rate of the call and of raw input files.")
+	flag.Float64Var(&speed, "speed", speed, "input pace relative to real time, 0 streams as fast as possible.")
+	flag.DurationVar(&silence, "silence", silence, "silence appended to each turn, so the server detects its end.")
+	flag.DurationVar(&timeout, "timeout", timeout, "maximum time to wait for the agent to answer a turn.")
+	flag.StringVar(&instruction, "instruction", instruction, "instruction to send to the agent.")
+	flag.BoolVar(&debug, "debug", false, "enable debug logs")
+	flag.Parse()
+
+	if len(inputs) == 0 {
+		log.Fatal("at least one -in file required")
+	}
+
+	slog.SetLogLoggerLevel(slog.LevelError)
+	if debug {
+		slog.SetLogLoggerLevel(slog.LevelDebug)
+	}
+
+	if err := run(inputs, output, sr, speed, silence, timeout, instruction); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(inputs []string, output string, sr int, speed float64, silence, timeout time.Duration, instruction string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := openairt.New(
+		openairt.WithDefaultLogger(),
+		openairt.WithSampleRate(sr),
+		openairt.WithInstruction(instruction),
+	)
+
+	answered := make(chan struct{}, 1)
+	client.OnError(func(e *events.ErrorEvent) {
+		slog.Error("error", slog.Any("error", e))
+	})
+	client.OnEvent(func(e any) {
+		switch x := e.(type) {
+		case *events.ResponseAudioTranscriptDoneEvent:
+			fmt.Println("agent>", x.Transcript)
+		case *events.ResponseDoneEvent:
+			// responses that only call tools are followed by another one
+			for _, o := range x.Response.Output {
+				if o.Type == "function_call" {
+					return
+				}
+			}
+			select {
+			case answered <- struct{}{}:
+			default:
+			}
+		}
+	})
+
+	if err := client.Open(ctx); err != nil {
+		return err
+	}
+
+	out, err := wav.Create(output, sr, 1)
+	if err != nil {
+		return err
+	}
+
+	var (
+		mu        sync.Mutex
+		lastWrite atomic.Int64
+		closed    bool
+	)
+
+	// record agent audio
+	agent := client.OutputAudio()
+	go func() {
+		buf := make([]byte, 3200)
+		for {
+			n, err := agent.Read(buf)
+			if err != nil {
+				if errors.Is(err, openairt.ErrInterrupted) {
+					continue
+				}
+				slog.Error("failed to read agent audio", slog.Any("err", err))
+				return
+			}
+
+			mu.Lock()
+			if !closed {
+				_, err = out.Write(buf[:n])
+			}
+			mu.Unlock()
+			if err != nil {
+				slog.Error("failed to record agent audio", slog.Any("err", err))
+				return
+			}
+			lastWrite.Store(time.Now().UnixNano())
+		}
+	}()
+
+	for _, in := range inputs {
+		fmt.Println("user>", in)
+
+		err := source.StreamFile(ctx, client.InputAudio(), in, source.Options{
+			SampleRate: sr,
+			Speed:      speed,
+			Silence:    silence,
+		})
+		if err != nil {
+			return err
+		}
+
+		select {
+		case <-answered:
+		case <-time.After(timeout):
+			return fmt.Errorf("no answer to %s within %s", in, timeout)
+		}
+	}
+
+	// all audio of the last answer has arrived with response.done, wait until
+	// it is written
+	for time.Since(time.Unix(0, lastWrite.Load())) < 200*time.Millisecond {
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	closed = true
+
+	return out.Close()
+}
@@ -0,0 +1,217 @@
+package openairt
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// OverflowPolicy decides what happens to audio written to Client.Audio when
+// the input buffer is full, e.g. because the connection stalls.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock blocks the writer until there is space.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropOldest discards the oldest buffered audio.
+	OverflowDropOldest
+	// OverflowDropNewest discards the audio that does not fit.
+	OverflowDropNewest
+)
+
+// InputStats are the counters of the input audio buffer in bytes of the
+// audio written to Client.Audio.
+type InputStats struct {
+	Written  int64
+	Dropped  int64
+	Buffered int
+}
+
+// inputBuffer holds the audio written to Client.Audio until it is sent.
+type inputBuffer struct {
+	mu         sync.Mutex
+	cond       *sync.Cond
+	data       []byte
+	size       int
+	sampleSize int
+	policy     OverflowPolicy
+	closed     bool
+	err        error  // the reason the input was closed, io.ErrClosedPipe by Close
+	conn       uint64 // the connection the input is sent on, see reopen
+	written    int64
+	dropped    int64
+	onDrop     func(n int64)
+}
+
+func newInputBuffer(size, sampleSize int, policy OverflowPolicy) *inputBuffer {
+	// whole samples only, so dropping keeps the alignment
+	size = max(sampleSize, size-size%sampleSize)
+
+	b := &inputBuffer{
+		data:       make([]byte, 0, size),
+		size:       size,
+		sampleSize: sampleSize,
+		policy:     policy,
+	}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+// Write buffers audio. Unless the policy is OverflowBlock it never blocks;
+// audio it drops is counted but not reported as an error.
+func (b *inputBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return 0, b.err
+	}
+	b.written += int64(len(p))
+	droppedBefore := b.dropped
+
+	switch b.policy {
+	case OverflowDropNewest:
+		free := b.size - len(b.data)
+		n := min(len(p), free-free%b.sampleSize)
+		b.data = append(b.data, p[:n]...)
+		b.dropped += int64(len(p) - n)
+	case OverflowDropOldest:
+		if over := len(b.data) + len(p) - b.size; over > 0 {
+			// whole samples, so the audio read stays aligned
+			over += (b.sampleSize - over%b.sampleSize) % b.sampleSize
+			n := min(over, len(b.data))
+			b.data = b.data[n:]
+			p = p[over-n:]
+			b.dropped += int64(over)
+		}
+		b.data = append(b.data, p...)
+	default:
+		for written := 0; written < len(p); {
+			for len(b.data) == b.size && !b.closed {
+				b.cond.Wait()
+			}
+			if b.closed {
+				return written, b.err
+			}
+			n := min(len(p)-written, b.size-len(b.data))
+			b.data = append(b.data, p[written:written+n]...)
+			written += n
+			b.cond.Broadcast()
+		}
+	}
+
+	if dropped := b.dropped - droppedBefore; dropped > 0 && b.onDrop != nil {
+		b.onDrop(dropped)
+	}
+
+	b.compact()
+	b.cond.Broadcast()
+
+	return len(p), nil
+}
+
+// compact moves the buffered audio to the start of its backing array, so
+// appending does not grow it.
+func (b *inputBuffer) compact() {
+	if cap(b.data)-len(b.data) < b.size/2 {
+		b.data = append(make([]byte, 0, b.size), b.data...)
+	}
+}
+
+// readChunk blocks until len(p) bytes are buffered and reads them. Less
+// audio is read once it waited for flush, so the end of an utterance is not
+// held back until more audio follows. After Close it returns the rest and
+// then io.EOF.
+func (b *inputBuffer) readChunk(p []byte, flush time.Duration) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var (
+		timer   *time.Timer
+		expired bool
+	)
+	for len(b.data) < len(p) && !b.closed && !(expired && len(b.data) >= b.sampleSize) {
+		if timer == nil && len(b.data) > 0 && flush > 0 {
+			timer = time.AfterFunc(flush, func() {
+				b.mu.Lock()
+				expired = true
+				b.mu.Unlock()
+				b.cond.Broadcast()
+			})
+			defer timer.Stop()
+		}
+		b.cond.Wait()
+	}
+	if len(b.data) == 0 {
+		return 0, io.EOF
+	}
+
+	n := min(len(p), len(b.data))
+	if !b.closed {
+		// whole samples only
+		n -= n % b.sampleSize
+	}
+	n = copy(p[:n], b.data)
+	b.data = b.data[n:]
+	b.cond.Broadcast()
+
+	return n, nil
+}
+
+// Close ends the input, buffered audio is still sent.
+func (b *inputBuffer) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.closed {
+		b.closed = true
+		b.err = io.ErrClosedPipe
+	}
+	b.cond.Broadcast()
+	return nil
+}
+
+// closeWithError ends the input because the connection conn ended. The
+// buffered audio is dropped and writes fail with err. A connection closes
+// its own input only, not the one of the next Open.
+func (b *inputBuffer) closeWithError(conn uint64, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if conn != b.conn {
+		return
+	}
+	if !b.closed {
+		b.closed = true
+		b.err = err
+	}
+	b.data = b.data[:0]
+	b.cond.Broadcast()
+}
+
+// reopen accepts input again after the buffer was closed, the audio
+// written before a first open is kept. It returns the connection to pass to
+// closeWithError.
+func (b *inputBuffer) reopen() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		b.closed = false
+		b.err = nil
+		b.data = b.data[:0]
+	}
+	b.conn++
+	return b.conn
+}
+
+func (b *inputBuffer) stats() InputStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return InputStats{
+		Written:  b.written,
+		Dropped:  b.dropped,
+		Buffered: len(b.data),
+	}
+}
@@ -0,0 +1,162 @@
+package openairt
+
+import (
+	"context"
+	"encoding/base64"
+	"github.com/codewandler/openairt-go/events"
+	"github.com/stretchr/testify/require"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestInputBufferDropOldest(t *testing.T) {
+	b := newInputBuffer(8, 2, OverflowDropOldest)
+
+	n, err := b.Write([]byte{1, 2, 3, 4, 5, 6})
+	require.NoError(t, err)
+	require.Equal(t, 6, n)
+	n, err = b.Write([]byte{7, 8, 9, 10})
+	require.NoError(t, err)
+	require.Equal(t, 4, n)
+
+	require.Equal(t, InputStats{Written: 10, Dropped: 2, Buffered: 8}, b.stats())
+
+	buf := make([]byte, 8)
+	n, err = b.readChunk(buf, 0)
+	require.NoError(t, err)
+	require.Equal(t, []byte{3, 4, 5, 6, 7, 8, 9, 10}, buf[:n])
+
+	// a write larger than the buffer keeps its end
+	_, err = b.Write([]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12})
+	require.NoError(t, err)
+	n, err = b.readChunk(buf, 0)
+	require.NoError(t, err)
+	require.Equal(t, []byte{5, 6, 7, 8, 9, 10, 11, 12}, buf[:n])
+	require.EqualValues(t, 6, b.stats().Dropped)
+
+	// odd writes drop whole samples
+	_, err = b.Write([]byte{1, 2, 3, 4, 5})
+	require.NoError(t, err)
+	_, err = b.Write([]byte{6, 7, 8, 9, 10})
+	require.NoError(t, err)
+	n, err = b.readChunk(buf, 0)
+	require.NoError(t, err)
+	require.Equal(t, []byte{3, 4, 5, 6, 7, 8, 9, 10}, buf[:n])
+	require.EqualValues(t, 8, b.stats().Dropped)
+}
+
+func TestInputBufferDropNewest(t *testing.T) {
+	b := newInputBuffer(8, 2, OverflowDropNewest)
+
+	_, err := b.Write([]byte{1, 2, 3, 4, 5, 6})
+	require.NoError(t, err)
+	n, err := b.Write([]byte{7, 8, 9, 10})
+	require.NoError(t, err)
+	require.Equal(t, 4, n)
+
+	require.Equal(t, InputStats{Written: 10, Dropped: 2, Buffered: 8}, b.stats())
+
+	buf := make([]byte, 8)
+	n, err = b.readChunk(buf, 0)
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 2, 3, 4, 5, 6, 7, 8}, buf[:n])
+}
+
+func TestInputBufferBlock(t *testing.T) {
+	b := newInputBuffer(4, 2, OverflowBlock)
+
+	written := make(chan error, 1)
+	go func() {
+		_, err := b.Write([]byte{1, 2, 3, 4, 5, 6, 7, 8})
+		written <- err
+	}()
+
+	select {
+	case <-written:
+		t.Fatal("write did not block")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	buf := make([]byte, 4)
+	n, err := b.readChunk(buf, 0)
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 2, 3, 4}, buf[:n])
+	require.NoError(t, <-written)
+
+	n, err = b.readChunk(buf, 0)
+	require.NoError(t, err)
+	require.Equal(t, []byte{5, 6, 7, 8}, buf[:n])
+	require.Equal(t, InputStats{Written: 8}, b.stats())
+}
+
+func TestInputBufferClose(t *testing.T) {
+	b := newInputBuffer(8, 2, OverflowBlock)
+
+	_, err := b.Write([]byte{1, 2})
+	require.NoError(t, err)
+	require.NoError(t, b.Close())
+
+	_, err = b.Write([]byte{3, 4})
+	require.ErrorIs(t, err, io.ErrClosedPipe)
+
+	// the rest is read before the end
+	buf := make([]byte, 4)
+	n, err := b.readChunk(buf, 0)
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 2}, buf[:n])
+
+	_, err = b.readChunk(buf, 0)
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestInputBufferFlush(t *testing.T) {
+	b := newInputBuffer(16, 2, OverflowBlock)
+
+	_, err := b.Write([]byte{1, 2, 3})
+	require.NoError(t, err)
+
+	// the partial chunk is read after the flush interval, whole samples only
+	start := time.Now()
+	buf := make([]byte, 8)
+	n, err := b.readChunk(buf, 50*time.Millisecond)
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 2}, buf[:n])
+	require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	_, err = b.Write([]byte{4, 5, 6, 7, 8, 9})
+	require.NoError(t, err)
+	n, err = b.readChunk(buf, 50*time.Millisecond)
+	require.NoError(t, err)
+	require.Equal(t, []byte{3, 4, 5, 6, 7, 8}, buf[:n])
+}
+
+func TestInputChunk(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, srv := openTestClient(t,
+		WithAudioFormat(events.AudioFormatG711ULaw),
+		WithInputChunk(100*time.Millisecond),
+		WithInputOverflow(OverflowDropNewest),
+	)
+
+	// 200ms of G.711 is sent in two appends of 800 bytes
+	_, err := client.Audio().Write(make([]byte, 1600))
+	require.NoError(t, err)
+
+	for range 2 {
+		evt, err := srv.Next(ctx, "input_audio_buffer.append")
+		require.NoError(t, err)
+
+		var msg struct {
+			Audio string `json:"audio"`
+		}
+		require.NoError(t, evt.Decode(&msg))
+		data, err := base64.StdEncoding.DecodeString(msg.Audio)
+		require.NoError(t, err)
+		require.Len(t, data, 800)
+	}
+
+	require.Equal(t, InputStats{Written: 1600}, client.InputStats())
+}
@@ -2,18 +2,25 @@ package websocket
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/gobwas/httphead"
 	"github.com/gobwas/ws"
 	"github.com/gobwas/ws/wsutil"
 	"io"
 	"log/slog"
+	"net"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// LevelTrace is the level of the frames sent and received.
+const LevelTrace = slog.LevelDebug - 4
+
 type HandlerFunc func(data []byte) error
 
 func Json[T any](j func(x T) error) HandlerFunc {
@@ -34,6 +41,84 @@ type ClientConfig struct {
 	OnText      func(data []byte) error
 	OnBinary    func(data []byte) error
 	Logger      *slog.Logger
+	// OnWrite is called with every text frame before it is sent.
+	OnWrite func(data []byte)
+	// WriteQueue is the number of messages queued for writing, 1000 by
+	// default. Write blocks while the queue is full.
+	WriteQueue int
+
+	// NetDial dials the TCP connection, net.Dialer by default.
+	NetDial NetDialFunc
+	// Proxy returns the proxy to tunnel through with CONNECT, e.g.
+	// http.ProxyFromEnvironment. Nil connects directly.
+	Proxy ProxyFunc
+	// TLSConfig is used for wss URLs, e.g. for custom root CAs or client
+	// certificates.
+	TLSConfig *tls.Config
+	// ProxyTLSConfig is used for https proxies, the default config verifying
+	// with the system roots if nil. TLSConfig is meant for the API only.
+	ProxyTLSConfig *tls.Config
+	// Compression offers the permessage-deflate extension to the server, nil
+	// disables it. Without the server's consent messages are uncompressed.
+	Compression *CompressionConfig
+
+	// PingInterval is the interval of the pings sent to the server, zero
+	// disables them.
+	PingInterval time.Duration
+	// PongTimeout is the time the server has to answer a ping before the
+	// connection counts as dead, zero disables the check.
+	PongTimeout time.Duration
+	// IdleTimeout closes the connection if nothing was received for that
+	// long, zero disables it.
+	IdleTimeout time.Duration
+	// WriteTimeout limits the time to write a frame, zero disables it.
+	WriteTimeout time.Duration
+	// OnDisconnect is called once with a *DisconnectError when the
+	// connection is lost, but not after Close.
+	OnDisconnect func(err error)
+}
+
+// DisconnectError is the reason a connection was lost.
+type DisconnectError struct {
+	// Reason describes the cause, e.g. "pong timeout" or "read failed".
+	Reason string
+	// Err is the underlying error, if any.
+	Err error
+}
+
+func (e *DisconnectError) Error() string {
+	if e.Err == nil {
+		return "websocket disconnected: " + e.Reason
+	}
+	return fmt.Sprintf("websocket disconnected: %s: %v", e.Reason, e.Err)
+}
+
+func (e *DisconnectError) Unwrap() error {
+	return e.Err
+}
+
+// Timeout reports whether the peer stopped responding.
+func (e *DisconnectError) Timeout() bool {
+	var ne net.Error
+	return e.Reason == reasonPongTimeout || (errors.As(e.Err, &ne) && ne.Timeout())
+}
+
+const reasonPongTimeout = "pong timeout"
+
+// ErrClosed is returned by writes after the connection ended.
+var ErrClosed = errors.New("websocket closed")
+
+// CloseError is the close frame received from the server.
+type CloseError struct {
+	Code   ws.StatusCode
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("websocket closed with code %d", e.Code)
+	}
+	return fmt.Sprintf("websocket closed with code %d: %s", e.Code, e.Reason)
 }
 
 type Client struct {
@@ -41,6 +126,77 @@ type Client struct {
 	done     chan struct{}
 	doneOnce sync.Once
 	logger   *slog.Logger
+
+	conn         net.Conn
+	closing      atomic.Bool
+	lastPing     atomic.Int64 // unix nanos of the last ping sent
+	lastPong     atomic.Int64 // unix nanos of the last pong received
+	onDisconnect func(err error)
+	failOnce     sync.Once
+	err          error // why the connection ended, set before done is closed
+	deflate      *deflate
+}
+
+// Compressed reports whether the server accepted the permessage-deflate
+// extension.
+func (c *Client) Compressed() bool {
+	return c.deflate != nil
+}
+
+// fail ends the connection with err, reporting it unless the connection
+// was closed with Close.
+func (c *Client) fail(err *DisconnectError) {
+	c.failOnce.Do(func() {
+		c.err = err
+		_ = c.conn.Close()
+		c.setDone()
+
+		if !c.closing.Load() {
+			c.logger.Debug("websocket disconnected", slog.Any("err", err))
+			if c.onDisconnect != nil {
+				c.onDisconnect(err)
+			}
+		}
+	})
+}
+
+// LastPong returns the time the last pong was received, zero if none.
+func (c *Client) LastPong() time.Time {
+	if n := c.lastPong.Load(); n != 0 {
+		return time.Unix(0, n)
+	}
+	return time.Time{}
+}
+
+// keepalive sends pings and fails the connection if they are not answered.
+func (c *Client) keepalive(ctx context.Context, interval, pongTimeout time.Duration) {
+	tick := interval
+	if pongTimeout > 0 {
+		tick = min(interval, pongTimeout)
+	}
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.done:
+			return
+		case now := <-ticker.C:
+			sent := c.lastPing.Load()
+			// without a timeout the pings only keep the connection busy
+			pending := pongTimeout > 0 && sent != 0 && c.lastPong.Load() < sent
+			switch {
+			case pending && now.Sub(time.Unix(0, sent)) > pongTimeout:
+				c.fail(&DisconnectError{Reason: reasonPongTimeout})
+				return
+			case !pending && now.Sub(time.Unix(0, sent)) >= interval:
+				c.lastPing.Store(now.UnixNano())
+				_ = c.Ping([]byte("ping"))
+			}
+		}
+	}
 }
 
 func (c *Client) setDone() {
@@ -49,34 +205,85 @@ func (c *Client) setDone() {
 	})
 }
 
-func (c *Client) WriteText(data []byte) {
-	c.Write(ws.OpText, data)
+// Done is closed when the connection ended.
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns nil while the connection is open and a *DisconnectError once
+// it ended. If the server closed it, the DisconnectError wraps a
+// *CloseError with the close code and reason.
+func (c *Client) Err() error {
+	select {
+	case <-c.done:
+		return c.err
+	default:
+		return nil
+	}
+}
+
+func (c *Client) WriteText(data []byte) error {
+	return c.Write(ws.OpText, data)
 }
 
-func (c *Client) WriteBinary(data []byte) {
-	c.Write(ws.OpBinary, data)
+func (c *Client) WriteBinary(data []byte) error {
+	return c.Write(ws.OpBinary, data)
 }
 
-func (c *Client) Ping(data []byte) {
-	c.Write(ws.OpPing, data)
+func (c *Client) Ping(data []byte) error {
+	return c.Write(ws.OpPing, data)
 }
 
-func (c *Client) SendClose(code ws.StatusCode, reason string) {
-	c.Write(ws.OpClose, ws.NewCloseFrameBody(code, reason))
+func (c *Client) SendClose(code ws.StatusCode, reason string) error {
+	return c.Write(ws.OpClose, ws.NewCloseFrameBody(code, reason))
 }
 
 func (c *Client) Close(ctx context.Context) error {
-	c.SendClose(ws.StatusNormalClosure, "closing")
+	c.closing.Store(true)
+	if err := c.SendClose(ws.StatusNormalClosure, "closing"); err != nil {
+		// already closed
+		return nil
+	}
 	select {
 	case <-c.done:
 		return nil
 	case <-ctx.Done():
+		_ = c.conn.Close()
 		return fmt.Errorf("close failed: %w", ctx.Err())
 	}
 }
 
-func (c *Client) Write(opcode ws.OpCode, data []byte) {
-	c.out <- wsutil.Message{OpCode: opcode, Payload: data}
+// Write queues a frame. It returns an error wrapping ErrClosed and the cause
+// once the connection ended.
+func (c *Client) Write(opcode ws.OpCode, data []byte) error {
+	return c.WriteContext(context.Background(), opcode, data)
+}
+
+// WriteContext is Write that gives up when ctx is done while the queue is
+// full.
+func (c *Client) WriteContext(ctx context.Context, opcode ws.OpCode, data []byte) error {
+	// a full queue must not win over a closed connection
+	select {
+	case <-c.done:
+		return c.closedErr()
+	default:
+	}
+
+	select {
+	case c.out <- wsutil.Message{OpCode: opcode, Payload: data}:
+		return nil
+	case <-c.done:
+		return c.closedErr()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *Client) closedErr() error {
+	if c.err == nil {
+		return ErrClosed
+	}
+	return fmt.Errorf("%w: %w", ErrClosed, c.err)
 }
 
 func Connect(ctx context.Context, config ClientConfig) (*Client, error) {
@@ -99,32 +306,54 @@ func Connect(ctx context.Context, config ClientConfig) (*Client, error) {
 	defer cancel()
 
 	// 2) Dial + WebSocket handshake
+	dial, err := config.dialer()
+	if err != nil {
+		return nil, err
+	}
 	d := ws.Dialer{
-		Timeout: config.DialTimeout,
-		Header:  ws.HandshakeHeaderHTTP(config.Headers),
+		Timeout:   config.DialTimeout,
+		Header:    ws.HandshakeHeaderHTTP(config.Headers),
+		NetDial:   dial,
+		TLSConfig: config.TLSConfig,
+	}
+	if config.Compression != nil {
+		d.Extensions = []httphead.Option{compressionParameters.Option()}
 	}
 	conn, buf, hs, err := d.Dial(hsCtx, config.URL)
 	if err != nil {
 		return nil, err
 	}
+	deflate, err := negotiateDeflate(config.Compression, hs.Extensions)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
 	logger.Debug("Handshake complete with response:", slog.Any("handshake", hs))
 
-	// Make sure to recycle the buffer if non-nil:
+	// frames sent right after the handshake may already be buffered
+	var reader io.Reader = conn
 	if buf != nil {
-		defer ws.PutReader(buf)
+		reader = io.MultiReader(buf, conn)
 	}
 
 	logger.Info("Connected to websocket", slog.Any("url", config.URL))
 
+	writeQueue := config.WriteQueue
+	if writeQueue <= 0 {
+		writeQueue = 1000
+	}
 	var (
 		input  = make(chan wsutil.Message, 1000)
-		output = make(chan wsutil.Message, 1000)
+		output = make(chan wsutil.Message, writeQueue)
 	)
 
 	client := &Client{
-		out:    output,
-		done:   make(chan struct{}),
-		logger: logger,
+		out:          output,
+		done:         make(chan struct{}),
+		logger:       logger,
+		conn:         conn,
+		onDisconnect: config.OnDisconnect,
+		deflate:      deflate,
 	}
 
 	onTextFunc := config.OnText
@@ -141,21 +370,35 @@ func Connect(ctx context.Context, config ClientConfig) (*Client, error) {
 	}
 
 	go func() {
-		defer client.setDone()
+		if buf != nil {
+			// Make sure to recycle the buffer if non-nil:
+			defer ws.PutReader(buf)
+		}
 		for {
+			if config.IdleTimeout > 0 {
+				_ = conn.SetReadDeadline(time.Now().Add(config.IdleTimeout))
+			}
 
-			messages, err := wsutil.ReadServerMessage(conn, nil)
-			if err != nil {
-				if errors.Is(err, io.EOF) {
+			messages, err := readMessages(reader, deflate)
+			// control messages may precede a failed read
+			for _, msg := range messages {
+				input <- msg
+				if msg.OpCode == ws.OpClose {
+					// the input loop ends the connection with the close code
 					return
 				}
-
-				logger.Error("ws read failed", slog.Any("err", err))
-
-				return
 			}
-			for _, msg := range messages {
-				input <- msg
+			if err != nil {
+				var ne net.Error
+				switch {
+				case errors.Is(err, io.EOF):
+					client.fail(&DisconnectError{Reason: "connection closed", Err: err})
+				case errors.As(err, &ne) && ne.Timeout():
+					client.fail(&DisconnectError{Reason: "idle timeout", Err: err})
+				default:
+					client.fail(&DisconnectError{Reason: "read failed", Err: err})
+				}
+				return
 			}
 		}
 	}()
@@ -165,11 +408,25 @@ func Connect(ctx context.Context, config ClientConfig) (*Client, error) {
 		for {
 			select {
 			case <-ctx.Done():
+				// cancelling the context of Connect closes the connection
+				client.closing.Store(true)
+				client.fail(&DisconnectError{Reason: "context done", Err: ctx.Err()})
+				return
+			case <-client.done:
 				return
 			case msg := <-output:
-				err := wsutil.WriteClientMessage(conn, msg.OpCode, msg.Payload)
+				if msg.OpCode == ws.OpText {
+					logger.Log(ctx, LevelTrace, "snd: text", slog.String("text", string(msg.Payload)))
+					if config.OnWrite != nil {
+						config.OnWrite(msg.Payload)
+					}
+				}
+				if config.WriteTimeout > 0 {
+					_ = conn.SetWriteDeadline(time.Now().Add(config.WriteTimeout))
+				}
+				err := writeMessage(conn, deflate, msg.OpCode, msg.Payload)
 				if err != nil {
-					logger.Error("Message write error:", slog.Any("err", err))
+					client.fail(&DisconnectError{Reason: "write failed", Err: err})
 					return
 				}
 
@@ -189,16 +446,19 @@ func Connect(ctx context.Context, config ClientConfig) (*Client, error) {
 
 				// handle control
 				if ws.OpCode.IsControl(msg.OpCode) {
-					logger.Debug("rcv: control", slog.Any("opcode", msg.OpCode), slog.Any("payload", msg.Payload))
+					logger.Log(ctx, LevelTrace, "rcv: control", slog.Any("opcode", msg.OpCode), slog.Any("payload", msg.Payload))
 
 					if err := wsutil.HandleServerControlMessage(conn, msg); err != nil {
 						logger.Error("handling of control messages failed", slog.Any("err", err))
 					}
 
 					switch msg.OpCode {
+					case ws.OpPong:
+						client.lastPong.Store(time.Now().UnixNano())
 					case ws.OpClose:
-						logger.Debug("rcv: close. closing client", slog.String("reason", string(msg.Payload)))
-						client.setDone()
+						code, reason := ws.ParseCloseFrameData(msg.Payload)
+						logger.Debug("rcv: close. closing client", slog.Int("code", int(code)), slog.String("reason", reason))
+						client.fail(&DisconnectError{Reason: "closed by server", Err: &CloseError{Code: code, Reason: reason}})
 					}
 
 					continue
@@ -206,13 +466,13 @@ func Connect(ctx context.Context, config ClientConfig) (*Client, error) {
 
 				switch msg.OpCode {
 				case ws.OpText:
-					logger.Debug("rcv: text", slog.String("text", string(msg.Payload)))
+					logger.Log(ctx, LevelTrace, "rcv: text", slog.String("text", string(msg.Payload)))
 					if err := onTextFunc(msg.Payload); err != nil {
 						logger.Error("text message handler failed", slog.Any("err", err))
 					}
 
 				case ws.OpBinary:
-					logger.Debug("rcv: binary", slog.Int("len", len(msg.Payload)))
+					logger.Log(ctx, LevelTrace, "rcv: binary", slog.Int("len", len(msg.Payload)))
 					if err := onBinaryFunc(msg.Payload); err != nil {
 						logger.Error("binary message handler failed", slog.Any("err", err))
 					}
@@ -221,7 +481,9 @@ func Connect(ctx context.Context, config ClientConfig) (*Client, error) {
 		}
 	}()
 
-	client.Ping([]byte("ping"))
+	if config.PingInterval > 0 {
+		go client.keepalive(ctx, config.PingInterval, config.PongTimeout)
+	}
 
 	return client, nil
 }
@@ -2,8 +2,15 @@ package websocket
 
 import (
 	"context"
+	"github.com/gobwas/ws"
+	"github.com/gobwas/ws/wsutil"
 	"github.com/stretchr/testify/require"
 	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -29,3 +36,203 @@ func TestClient(t *testing.T) {
 
 	<-ctx.Done()
 }
+
+// testServer upgrades connections and hands them to handle.
+func testServer(t *testing.T, handle func(conn net.Conn)) string {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, _, _, err := ws.UpgradeHTTP(r, w)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		handle(conn)
+	}))
+	t.Cleanup(srv.Close)
+
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+// echoControl answers pings and close frames until the connection closes.
+func echoControl(conn net.Conn) {
+	for {
+		if _, _, err := wsutil.ReadClientData(conn); err != nil {
+			return
+		}
+	}
+}
+
+func connectDisconnect(t *testing.T, config ClientConfig) (*Client, <-chan error) {
+	t.Helper()
+
+	disconnects := make(chan error, 1)
+	config.Logger = slog.New(slog.DiscardHandler)
+	config.OnDisconnect = func(err error) {
+		disconnects <- err
+	}
+
+	client, err := Connect(t.Context(), config)
+	require.NoError(t, err)
+	return client, disconnects
+}
+
+func TestClientPongTimeout(t *testing.T) {
+	// the peer never reads, so it never answers pings
+	hold := make(chan struct{})
+	defer close(hold)
+	url := testServer(t, func(conn net.Conn) { <-hold })
+
+	client, disconnects := connectDisconnect(t, ClientConfig{
+		URL:          url,
+		PingInterval: 20 * time.Millisecond,
+		PongTimeout:  50 * time.Millisecond,
+	})
+
+	select {
+	case err := <-disconnects:
+		var de *DisconnectError
+		require.ErrorAs(t, err, &de)
+		require.Equal(t, "pong timeout", de.Reason)
+		require.True(t, de.Timeout())
+	case <-time.After(2 * time.Second):
+		t.Fatal("no disconnect")
+	}
+
+	select {
+	case <-client.done:
+	case <-time.After(time.Second):
+		t.Fatal("client not done")
+	}
+}
+
+func TestClientPingWithoutPongTimeout(t *testing.T) {
+	// the peer reads the pings but never answers them
+	var pings atomic.Int32
+	url := testServer(t, func(conn net.Conn) {
+		for {
+			frame, err := ws.ReadFrame(conn)
+			if err != nil {
+				return
+			}
+			if frame.Header.OpCode == ws.OpPing {
+				pings.Add(1)
+			}
+		}
+	})
+
+	_, disconnects := connectDisconnect(t, ClientConfig{
+		URL:          url,
+		PingInterval: 10 * time.Millisecond,
+	})
+
+	// unanswered pings are no reason to disconnect or to stop pinging
+	select {
+	case err := <-disconnects:
+		t.Fatalf("unexpected disconnect: %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+	require.Greater(t, pings.Load(), int32(5))
+}
+
+func TestClientIdleTimeout(t *testing.T) {
+	hold := make(chan struct{})
+	defer close(hold)
+	url := testServer(t, func(conn net.Conn) { <-hold })
+
+	_, disconnects := connectDisconnect(t, ClientConfig{
+		URL:         url,
+		IdleTimeout: 50 * time.Millisecond,
+	})
+
+	select {
+	case err := <-disconnects:
+		var de *DisconnectError
+		require.ErrorAs(t, err, &de)
+		require.Equal(t, "idle timeout", de.Reason)
+		require.True(t, de.Timeout())
+	case <-time.After(2 * time.Second):
+		t.Fatal("no disconnect")
+	}
+}
+
+func TestClientKeepaliveAndClose(t *testing.T) {
+	url := testServer(t, echoControl)
+
+	client, disconnects := connectDisconnect(t, ClientConfig{
+		URL:          url,
+		PingInterval: 10 * time.Millisecond,
+		PongTimeout:  50 * time.Millisecond,
+		IdleTimeout:  50 * time.Millisecond,
+	})
+
+	// pongs keep the connection alive past the idle timeout
+	select {
+	case err := <-disconnects:
+		t.Fatalf("unexpected disconnect: %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+	require.False(t, client.LastPong().IsZero())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, client.Close(ctx))
+
+	select {
+	case err := <-disconnects:
+		t.Fatalf("disconnect reported after close: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestClientClosedByServer(t *testing.T) {
+	url := testServer(t, func(conn net.Conn) {
+		body := ws.NewCloseFrameBody(ws.StatusCode(4000), "bye")
+		_ = ws.WriteFrame(conn, ws.NewCloseFrame(body))
+		echoControl(conn)
+	})
+
+	client, disconnects := connectDisconnect(t, ClientConfig{URL: url})
+
+	select {
+	case <-client.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("client not done")
+	}
+
+	var ce *CloseError
+	require.ErrorAs(t, client.Err(), &ce)
+	require.Equal(t, ws.StatusCode(4000), ce.Code)
+	require.Equal(t, "bye", ce.Reason)
+	require.ErrorAs(t, <-disconnects, &ce)
+
+	// writes fail instead of filling the queue and blocking
+	for range 2 * cap(client.out) {
+		err := client.WriteText([]byte(`{}`))
+		require.ErrorIs(t, err, ErrClosed)
+		require.ErrorAs(t, err, &ce)
+	}
+}
+
+func TestClientWriteContext(t *testing.T) {
+	hold := make(chan struct{})
+	defer close(hold)
+	url := testServer(t, func(conn net.Conn) { <-hold })
+
+	client, _ := connectDisconnect(t, ClientConfig{URL: url, WriteQueue: 4})
+	require.NoError(t, client.Err())
+	require.Equal(t, 4, cap(client.out))
+
+	// the peer does not read, so the writer blocks and the queue fills up
+	payload := make([]byte, 1<<20)
+	var err error
+	for range cap(client.out) + 100 {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		err = client.WriteContext(ctx, ws.OpBinary, payload)
+		cancel()
+		if err != nil {
+			break
+		}
+	}
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,186 @@
+package websocket
+
+import (
+	"bytes"
+	"compress/flate"
+	"fmt"
+	"github.com/gobwas/httphead"
+	"github.com/gobwas/ws"
+	"github.com/gobwas/ws/wsflate"
+	"github.com/gobwas/ws/wsutil"
+	"io"
+)
+
+// CompressionConfig enables the permessage-deflate extension, see
+// ClientConfig.Compression. Without context takeover every message is
+// compressed on its own, which keeps the memory per connection small.
+type CompressionConfig struct {
+	// Level is the flate level, flate.HuffmanOnly by default. Base64 audio
+	// has few repetitions, so Huffman coding saves about as much as
+	// flate.BestCompression at a fraction of the CPU, see BenchmarkAppend.
+	Level int
+	// MinSize is the size below which messages are sent uncompressed, 256
+	// bytes by default.
+	MinSize int
+}
+
+// compressionParameters are offered to the server. Both sides compress
+// every message without the context of the previous ones.
+var compressionParameters = wsflate.Parameters{
+	ServerNoContextTakeover: true,
+	ClientNoContextTakeover: true,
+}
+
+// deflate compresses the messages written and decompresses the ones read
+// once the server accepted the extension. The writer and reader are used by
+// one goroutine each.
+type deflate struct {
+	minSize int
+	w       *wsflate.Writer
+	wbuf    bytes.Buffer
+	r       *wsflate.Reader
+}
+
+// negotiateDeflate returns the deflate of the extension accepted in the
+// handshake, nil if the server declined it.
+func negotiateDeflate(config *CompressionConfig, accepted []httphead.Option) (*deflate, error) {
+	if config == nil {
+		return nil, nil
+	}
+
+	for _, opt := range accepted {
+		if string(opt.Name) != wsflate.ExtensionName {
+			continue
+		}
+
+		var params wsflate.Parameters
+		if err := params.Parse(opt); err != nil {
+			return nil, fmt.Errorf("permessage-deflate: %w", err)
+		}
+		if !params.ServerNoContextTakeover {
+			return nil, fmt.Errorf("permessage-deflate: server did not accept server_no_context_takeover")
+		}
+
+		return newDeflate(*config)
+	}
+
+	return nil, nil
+}
+
+func newDeflate(config CompressionConfig) (*deflate, error) {
+	level := config.Level
+	if level == 0 {
+		level = flate.HuffmanOnly
+	}
+	if _, err := flate.NewWriter(io.Discard, level); err != nil {
+		return nil, fmt.Errorf("permessage-deflate: %w", err)
+	}
+	minSize := config.MinSize
+	if minSize == 0 {
+		minSize = 256
+	}
+
+	d := &deflate{minSize: minSize}
+	d.w = wsflate.NewWriter(nil, func(w io.Writer) wsflate.Compressor {
+		f, _ := flate.NewWriter(w, level)
+		return f
+	})
+	d.r = wsflate.NewReader(nil, func(r io.Reader) wsflate.Decompressor {
+		return &flateReader{flate.NewReader(r)}
+	})
+	return d, nil
+}
+
+// compress returns the compressed payload of a data frame, or nil if it is
+// too small to be worth it. The result is valid until the next call.
+func (d *deflate) compress(p []byte) ([]byte, error) {
+	if len(p) < d.minSize {
+		return nil, nil
+	}
+
+	d.wbuf.Reset()
+	d.w.Reset(&d.wbuf)
+	if _, err := d.w.Write(p); err != nil {
+		return nil, err
+	}
+	if err := d.w.Flush(); err != nil {
+		return nil, err
+	}
+	return d.wbuf.Bytes(), nil
+}
+
+func (d *deflate) decompress(p []byte) ([]byte, error) {
+	d.r.Reset(bytes.NewReader(p))
+	return io.ReadAll(d.r)
+}
+
+// flateReader makes the flate reader resettable without allocating a new
+// one per message.
+type flateReader struct {
+	io.ReadCloser
+}
+
+func (f *flateReader) Reset(r io.Reader) {
+	_ = f.ReadCloser.(flate.Resetter).Reset(r, nil)
+}
+
+// writeMessage writes a data or control frame, compressed if possible.
+func writeMessage(w io.Writer, d *deflate, op ws.OpCode, payload []byte) error {
+	if d == nil || op.IsControl() {
+		return wsutil.WriteClientMessage(w, op, payload)
+	}
+
+	compressed, err := d.compress(payload)
+	if err != nil {
+		return err
+	}
+	if compressed == nil {
+		return wsutil.WriteClientMessage(w, op, payload)
+	}
+
+	frame := ws.NewFrame(op, true, compressed)
+	frame.Header.Rsv = ws.Rsv(true, false, false)
+	return ws.WriteFrame(w, ws.MaskFrameInPlace(frame))
+}
+
+// readMessages reads the next data message and the control messages before
+// it, like wsutil.ReadServerMessage, decompressing the data message if
+// needed.
+func readMessages(r io.Reader, d *deflate) ([]wsutil.Message, error) {
+	if d == nil {
+		return wsutil.ReadServerMessage(r, nil)
+	}
+
+	var (
+		messages []wsutil.Message
+		state    wsflate.MessageState
+	)
+	rd := wsutil.Reader{
+		Source:     r,
+		State:      ws.StateClientSide | ws.StateExtended,
+		Extensions: []wsutil.RecvExtension{&state},
+		OnIntermediate: func(hdr ws.Header, src io.Reader) error {
+			payload, err := io.ReadAll(src)
+			if err != nil {
+				return err
+			}
+			messages = append(messages, wsutil.Message{OpCode: hdr.OpCode, Payload: payload})
+			return nil
+		},
+	}
+
+	hdr, err := rd.NextFrame()
+	if err != nil {
+		return messages, err
+	}
+	payload, err := io.ReadAll(&rd)
+	if err != nil {
+		return messages, err
+	}
+	if state.IsCompressed() {
+		if payload, err = d.decompress(payload); err != nil {
+			return messages, fmt.Errorf("permessage-deflate: %w", err)
+		}
+	}
+	return append(messages, wsutil.Message{OpCode: hdr.OpCode, Payload: payload}), nil
+}
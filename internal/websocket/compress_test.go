@@ -0,0 +1,213 @@
+package websocket
+
+import (
+	"compress/flate"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"github.com/gobwas/ws"
+	"github.com/gobwas/ws/wsflate"
+	"github.com/gobwas/ws/wsutil"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// deflateServer starts a websocket server that accepts permessage-deflate
+// and echoes every data message. It reports whether the messages it read
+// were compressed.
+func deflateServer(t testing.TB, accept bool) (string, <-chan bool) {
+	t.Helper()
+
+	compressed := make(chan bool, 1000)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ext := wsflate.Extension{Parameters: wsflate.DefaultParameters}
+		upgrader := ws.HTTPUpgrader{}
+		if accept {
+			upgrader.Negotiate = ext.Negotiate
+		}
+		conn, _, _, err := upgrader.Upgrade(r, w)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, accepted := ext.Accepted()
+		// the server compresses like the client, every message on its own
+		d, _ := newDeflate(CompressionConfig{MinSize: 1})
+
+		for {
+			var state wsflate.MessageState
+			rd := wsutil.Reader{
+				Source:         conn,
+				State:          ws.StateServerSide | ws.StateExtended,
+				Extensions:     []wsutil.RecvExtension{&state},
+				OnIntermediate: wsutil.ControlFrameHandler(conn, ws.StateServerSide),
+			}
+			hdr, err := rd.NextFrame()
+			if err != nil {
+				return
+			}
+			payload, err := io.ReadAll(&rd)
+			if err != nil {
+				return
+			}
+			select {
+			case compressed <- state.IsCompressed():
+			default:
+			}
+
+			if !accepted {
+				_ = wsutil.WriteServerMessage(conn, hdr.OpCode, payload)
+				continue
+			}
+			if state.IsCompressed() {
+				if payload, err = d.decompress(payload); err != nil {
+					return
+				}
+			}
+			compressed, err := d.compress(payload)
+			if err != nil {
+				return
+			}
+			frame := ws.NewFrame(hdr.OpCode, true, compressed)
+			frame.Header.Rsv = ws.Rsv(true, false, false)
+			if err := ws.WriteFrame(conn, frame); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return "ws" + strings.TrimPrefix(srv.URL, "http"), compressed
+}
+
+// appendEvent returns an input_audio_buffer.append event with 20ms of PCM16
+// at 24 kHz: a tone with some noise, roughly as compressible as speech.
+func appendEvent(rnd *rand.Rand, n int) []byte {
+	pcm := make([]byte, 960)
+	for i := 0; i < len(pcm)/2; i++ {
+		t := float64(n*len(pcm)/2+i) / 24_000
+		v := 3000*math.Sin(2*math.Pi*220*t) + 300*rnd.NormFloat64()
+		binary.LittleEndian.PutUint16(pcm[2*i:], uint16(int16(v)))
+	}
+
+	data, _ := json.Marshal(map[string]any{
+		"event_id": fmt.Sprintf("evt_%d", n),
+		"type":     "input_audio_buffer.append",
+		"audio":    base64.StdEncoding.EncodeToString(pcm),
+	})
+	return data
+}
+
+func TestCompression(t *testing.T) {
+	url, compressed := deflateServer(t, true)
+
+	received := make(chan []byte, 10)
+	client, err := Connect(t.Context(), ClientConfig{
+		URL:         url,
+		Logger:      slog.New(slog.DiscardHandler),
+		Compression: &CompressionConfig{},
+		OnText: func(data []byte) error {
+			received <- data
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	require.True(t, client.Compressed())
+
+	event := appendEvent(rand.New(rand.NewPCG(1, 2)), 0)
+	require.NoError(t, client.WriteText(event))
+	require.True(t, <-compressed)
+	require.Equal(t, event, <-received)
+
+	// small messages are not worth it
+	require.NoError(t, client.WriteText([]byte(`{"type":"response.create"}`)))
+	require.False(t, <-compressed)
+	require.Equal(t, `{"type":"response.create"}`, string(<-received))
+}
+
+func TestCompressionDeclined(t *testing.T) {
+	url, compressed := deflateServer(t, false)
+
+	received := make(chan []byte, 10)
+	client, err := Connect(t.Context(), ClientConfig{
+		URL:         url,
+		Logger:      slog.New(slog.DiscardHandler),
+		Compression: &CompressionConfig{},
+		OnText: func(data []byte) error {
+			received <- data
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	require.False(t, client.Compressed())
+
+	event := appendEvent(rand.New(rand.NewPCG(1, 2)), 0)
+	require.NoError(t, client.WriteText(event))
+	require.False(t, <-compressed)
+	require.Equal(t, event, <-received)
+}
+
+// countingWriter counts the bytes written.
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// BenchmarkAppend frames input_audio_buffer.append events as the writer of
+// the client does and reports the bytes on the wire per event next to the
+// CPU time.
+func BenchmarkAppend(b *testing.B) {
+	rnd := rand.New(rand.NewPCG(1, 2))
+	events := make([][]byte, 50)
+	var size int
+	for i := range events {
+		events[i] = appendEvent(rnd, i)
+		size += len(events[i])
+	}
+
+	for _, bc := range []struct {
+		name        string
+		compression *CompressionConfig
+	}{
+		{"uncompressed", nil},
+		{"huffman-only", &CompressionConfig{Level: flate.HuffmanOnly}},
+		{"deflate-speed", &CompressionConfig{Level: flate.BestSpeed}},
+		{"deflate-default", &CompressionConfig{Level: flate.DefaultCompression}},
+		{"deflate-best", &CompressionConfig{Level: flate.BestCompression}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			var d *deflate
+			if bc.compression != nil {
+				var err error
+				d, err = newDeflate(*bc.compression)
+				require.NoError(b, err)
+			}
+
+			var w countingWriter
+			payload := make([]byte, 0, 2048)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				// uncompressed frames are masked in place
+				payload = append(payload[:0], events[i%len(events)]...)
+				require.NoError(b, writeMessage(&w, d, ws.OpText, payload))
+			}
+			b.StopTimer()
+
+			b.ReportMetric(float64(size)/float64(len(events)), "json-B/op")
+			b.ReportMetric(float64(w.n)/float64(b.N), "wire-B/op")
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// NetDialFunc dials a plain connection, like net.Dialer.DialContext.
+type NetDialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// ProxyFunc returns the proxy for a request, nil for none, like
+// http.ProxyFromEnvironment.
+type ProxyFunc func(req *http.Request) (*url.URL, error)
+
+// dialer returns the dial function of the config, tunnelling through the
+// proxy of the URL if there is one.
+func (config ClientConfig) dialer() (NetDialFunc, error) {
+	dial := config.NetDial
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	if config.Proxy == nil {
+		return dial, nil
+	}
+
+	u, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, err
+	}
+	// the proxy is chosen as for the HTTP request of the handshake
+	switch u.Scheme {
+	case "ws":
+		u.Scheme = "http"
+	case "wss":
+		u.Scheme = "https"
+	}
+	proxy, err := config.Proxy(&http.Request{Method: http.MethodGet, URL: u, Host: u.Host})
+	if err != nil {
+		return nil, fmt.Errorf("proxy: %w", err)
+	}
+	if proxy == nil {
+		return dial, nil
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return dialProxy(ctx, dial, config.ProxyTLSConfig, proxy, addr)
+	}, nil
+}
+
+// dialProxy opens a tunnel to addr with a CONNECT request to the proxy.
+func dialProxy(ctx context.Context, dial NetDialFunc, tlsConfig *tls.Config, proxy *url.URL, addr string) (net.Conn, error) {
+	proxyAddr := proxy.Host
+	if proxy.Port() == "" {
+		if proxy.Scheme == "https" {
+			proxyAddr = net.JoinHostPort(proxy.Hostname(), "443")
+		} else {
+			proxyAddr = net.JoinHostPort(proxy.Hostname(), "80")
+		}
+	}
+
+	conn, err := dial(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, fmt.Errorf("dial proxy: %w", err)
+	}
+
+	if proxy.Scheme == "https" {
+		config := &tls.Config{}
+		if tlsConfig != nil {
+			config = tlsConfig.Clone()
+		}
+		if config.ServerName == "" {
+			config.ServerName = proxy.Hostname()
+		}
+		conn = tls.Client(conn, config)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: http.Header{},
+	}
+	if user := proxy.User; user != nil {
+		password, _ := user.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := req.Write(conn); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("proxy connect: %w", err)
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("proxy connect: %w", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		_ = conn.Close()
+		return nil, fmt.Errorf("proxy connect: %s", strings.TrimSpace(resp.Status))
+	}
+
+	if br.Buffered() > 0 {
+		// the server spoke before the client, keep what was read
+		return &bufferedConn{Conn: conn, r: br}, nil
+	}
+	return conn, nil
+}
+
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
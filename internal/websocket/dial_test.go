@@ -0,0 +1,225 @@
+package websocket
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/gobwas/ws"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// clientCert returns a self-signed client certificate.
+func clientCert(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "openairt test client"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: cert}, pool
+}
+
+// tlsServer starts a websocket server that requires a client certificate
+// signed by clients.
+func tlsServer(t *testing.T, clients *x509.CertPool) (string, *x509.CertPool) {
+	t.Helper()
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, _, _, err := ws.UpgradeHTTP(r, w)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		echoControl(conn)
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clients}
+	// rejected handshakes are expected
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+	return "wss" + strings.TrimPrefix(srv.URL, "https"), roots
+}
+
+// connectProxy starts a CONNECT proxy, an https one if secure, and returns
+// its URL, its certificate and the addresses it tunnelled to.
+func connectProxy(t *testing.T, user *url.Userinfo, secure bool) (*url.URL, *x509.Certificate, <-chan string) {
+	t.Helper()
+
+	targets := make(chan string, 10)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			http.Error(w, "CONNECT only", http.StatusMethodNotAllowed)
+			return
+		}
+		if user != nil {
+			username, password, ok := (&http.Request{Header: http.Header{
+				"Authorization": r.Header["Proxy-Authorization"],
+			}}).BasicAuth()
+			expected, _ := user.Password()
+			if !ok || username != user.Username() || password != expected {
+				w.WriteHeader(http.StatusProxyAuthRequired)
+				return
+			}
+		}
+
+		target, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer target.Close()
+		targets <- r.Host
+
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+
+		go func() {
+			_, _ = io.Copy(target, conn)
+		}()
+		_, _ = io.Copy(conn, target)
+	}))
+	if secure {
+		// rejected handshakes are expected
+		srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+		srv.StartTLS()
+	} else {
+		srv.Start()
+	}
+	t.Cleanup(srv.Close)
+
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+	u.User = user
+	return u, srv.Certificate(), targets
+}
+
+func TestConnectTLS(t *testing.T) {
+	cert, clients := clientCert(t)
+	url, roots := tlsServer(t, clients)
+
+	config := ClientConfig{
+		URL:       url,
+		Logger:    slog.New(slog.DiscardHandler),
+		TLSConfig: &tls.Config{RootCAs: roots, Certificates: []tls.Certificate{cert}},
+	}
+	client, err := Connect(t.Context(), config)
+	require.NoError(t, err)
+	require.NoError(t, client.Close(t.Context()))
+
+	// the server requires the client certificate
+	config.TLSConfig = &tls.Config{RootCAs: roots}
+	_, err = Connect(t.Context(), config)
+	require.Error(t, err)
+
+	// and the client the root CA of the server
+	config.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	_, err = Connect(t.Context(), config)
+	require.Error(t, err)
+}
+
+func TestConnectProxy(t *testing.T) {
+	cert, clients := clientCert(t)
+	target, roots := tlsServer(t, clients)
+	proxy, _, targets := connectProxy(t, url.UserPassword("user", "secret"), false)
+
+	client, err := Connect(t.Context(), ClientConfig{
+		URL:       target,
+		Logger:    slog.New(slog.DiscardHandler),
+		Proxy:     http.ProxyURL(proxy),
+		TLSConfig: &tls.Config{RootCAs: roots, Certificates: []tls.Certificate{cert}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, client.Close(t.Context()))
+	require.Equal(t, strings.TrimPrefix(target, "wss://"), <-targets)
+
+	proxy.User = url.UserPassword("user", "wrong")
+	_, err = Connect(t.Context(), ClientConfig{
+		URL:    target,
+		Logger: slog.New(slog.DiscardHandler),
+		Proxy:  http.ProxyURL(proxy),
+	})
+	require.ErrorContains(t, err, "407")
+}
+
+func TestConnectHTTPSProxy(t *testing.T) {
+	cert, clients := clientCert(t)
+	target, roots := tlsServer(t, clients)
+	proxy, proxyCert, targets := connectProxy(t, nil, true)
+	proxyRoots := x509.NewCertPool()
+	proxyRoots.AddCert(proxyCert)
+
+	config := ClientConfig{
+		URL:            target,
+		Logger:         slog.New(slog.DiscardHandler),
+		Proxy:          http.ProxyURL(proxy),
+		TLSConfig:      &tls.Config{RootCAs: roots, Certificates: []tls.Certificate{cert}},
+		ProxyTLSConfig: &tls.Config{RootCAs: proxyRoots},
+	}
+	client, err := Connect(t.Context(), config)
+	require.NoError(t, err)
+	require.NoError(t, client.Close(t.Context()))
+	require.Equal(t, strings.TrimPrefix(target, "wss://"), <-targets)
+
+	// the roots of the API do not apply to the proxy
+	apiRoots := roots.Clone()
+	apiRoots.AddCert(proxyCert)
+	config.TLSConfig = &tls.Config{RootCAs: apiRoots, Certificates: []tls.Certificate{cert}}
+	config.ProxyTLSConfig = nil
+	_, err = Connect(t.Context(), config)
+	require.ErrorContains(t, err, "certificate")
+}
+
+func TestConnectNetDial(t *testing.T) {
+	url := testServer(t, echoControl)
+
+	var dials atomic.Int32
+	client, err := Connect(t.Context(), ClientConfig{
+		URL:    url,
+		Logger: slog.New(slog.DiscardHandler),
+		NetDial: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			dials.Add(1)
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	})
+	require.NoError(t, err)
+	require.NoError(t, client.Close(t.Context()))
+	require.EqualValues(t, 1, dials.Load())
+}
@@ -0,0 +1,68 @@
+package openairt
+
+import (
+	"errors"
+	"github.com/codewandler/openairt-go/internal/websocket"
+	"log/slog"
+	"time"
+)
+
+// DisconnectError is passed to the OnDisconnect handler when the connection
+// to the API is lost. Timeout reports whether the server stopped responding.
+type DisconnectError = websocket.DisconnectError
+
+// CloseError is the close code and reason sent by the server, wrapped by the
+// DisconnectError of a connection the server closed.
+type CloseError = websocket.CloseError
+
+// ErrNotConnected is returned when sending before Open.
+var ErrNotConnected = errors.New("not connected")
+
+// ErrClosed is wrapped by the errors of Send once the connection was lost.
+var ErrClosed = websocket.ErrClosed
+
+// KeepaliveConfig configures the detection of dead connections, see
+// WithKeepalive. Zero durations disable the respective check.
+type KeepaliveConfig struct {
+	// PingInterval is the interval of the pings sent to the server.
+	PingInterval time.Duration
+	// PongTimeout is the time the server has to answer a ping. Without it
+	// unanswered pings go unnoticed.
+	PongTimeout time.Duration
+	// IdleTimeout closes the connection if nothing, not even a pong, was
+	// received for that long.
+	IdleTimeout time.Duration
+	// WriteTimeout limits the time to write a message.
+	WriteTimeout time.Duration
+}
+
+// DefaultKeepalive pings every 20s and gives up on a connection that did
+// not answer within 10s or was silent for a minute.
+var DefaultKeepalive = KeepaliveConfig{
+	PingInterval: 20 * time.Second,
+	PongTimeout:  10 * time.Second,
+	IdleTimeout:  time.Minute,
+	WriteTimeout: 10 * time.Second,
+}
+
+// OnDisconnect sets a handler called once when the connection is lost, e.g.
+// because the server did not answer the pings. It is not called after
+// Close. It may be set while the client is open.
+func (c *Client) OnDisconnect(h func(err *DisconnectError)) {
+	c.onDisconnect.Store(&h)
+}
+
+func (c *Client) handleDisconnect(input uint64, err error) {
+	var de *DisconnectError
+	if !errors.As(err, &de) {
+		de = &DisconnectError{Reason: "unknown", Err: err}
+	}
+
+	c.log().Warn("connection lost", slog.String("reason", de.Reason), slog.Any("err", de.Err))
+	c.telemetry.endSession()
+	c.closeInput(input, de)
+
+	if h := c.onDisconnect.Load(); h != nil && *h != nil {
+		(*h)(de)
+	}
+}
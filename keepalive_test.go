@@ -0,0 +1,164 @@
+package openairt
+
+import (
+	"context"
+	"github.com/codewandler/openairt-go/events"
+	"github.com/codewandler/openairt-go/openairttest"
+	"github.com/gobwas/ws"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOnDisconnect(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	srv := openairttest.NewServer()
+	defer srv.Close()
+
+	client := New(WithKey("test"), WithBaseURL(srv.URL), WithKeepalive(KeepaliveConfig{
+		PingInterval: 10 * time.Millisecond,
+		PongTimeout:  100 * time.Millisecond,
+	}))
+	disconnects := make(chan *DisconnectError, 1)
+	client.OnDisconnect(func(err *DisconnectError) {
+		disconnects <- err
+	})
+	require.NoError(t, client.Open(ctx))
+
+	// answered pings keep the connection open
+	select {
+	case err := <-disconnects:
+		t.Fatalf("unexpected disconnect: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	srv.Close()
+
+	select {
+	case err := <-disconnects:
+		// depending on the timing a read or a ping fails first
+		require.NotEmpty(t, err.Reason)
+		require.False(t, err.Timeout())
+	case <-ctx.Done():
+		t.Fatal("no disconnect")
+	}
+}
+
+func TestSendErrors(t *testing.T) {
+	require.ErrorIs(t, New(WithKey("test")).Send(events.InputAudioBufferCommitEvent{}), ErrNotConnected)
+
+	client, srv := openTestClient(t)
+	disconnected := make(chan struct{})
+	client.OnDisconnect(func(err *DisconnectError) {
+		close(disconnected)
+	})
+	require.NoError(t, client.Send(events.InputAudioBufferCommitEvent{}))
+
+	srv.Close()
+	select {
+	case <-disconnected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no disconnect")
+	}
+
+	err := client.Send(events.InputAudioBufferCommitEvent{})
+	require.ErrorIs(t, err, ErrClosed)
+	var de *DisconnectError
+	require.ErrorAs(t, err, &de)
+}
+
+func TestOpenClosedByServer(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// the server rejects the session instead of creating it
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, _, _, err := ws.UpgradeHTTP(r, w)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_ = ws.WriteFrame(conn, ws.NewCloseFrame(ws.NewCloseFrameBody(4000, "invalid session")))
+		_, _ = io.Copy(io.Discard, conn)
+	}))
+	defer srv.Close()
+
+	client := New(WithKey("test"), WithBaseURL("ws"+strings.TrimPrefix(srv.URL, "http")))
+	err := client.Open(ctx)
+	var ce *CloseError
+	require.ErrorAs(t, err, &ce)
+	require.EqualValues(t, 4000, ce.Code)
+	require.Equal(t, "invalid session", ce.Reason)
+}
+
+func TestInputAudioAfterDisconnect(t *testing.T) {
+	client, srv := openTestClient(t, WithInputBuffer(100*time.Millisecond), WithInputOverflow(OverflowBlock))
+	disconnected := make(chan struct{})
+	client.OnDisconnect(func(err *DisconnectError) {
+		close(disconnected)
+	})
+
+	srv.Close()
+	select {
+	case <-disconnected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no disconnect")
+	}
+
+	// three times the buffer fails instead of blocking
+	written := make(chan error, 1)
+	go func() {
+		_, err := client.InputAudio().Write(make([]byte, 3*4800))
+		written <- err
+	}()
+	select {
+	case err := <-written:
+		require.ErrorIs(t, err, ErrClosed)
+		var de *DisconnectError
+		require.ErrorAs(t, err, &de)
+	case <-time.After(time.Second):
+		t.Fatal("write blocked")
+	}
+}
+
+func TestInputAudioAfterContextDone(t *testing.T) {
+	srv := openairttest.NewServer()
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	client := New(WithKey("test"), WithBaseURL(srv.URL), WithInputBuffer(100*time.Millisecond), WithInputOverflow(OverflowBlock))
+	disconnected := make(chan struct{}, 1)
+	client.OnDisconnect(func(err *DisconnectError) {
+		disconnected <- struct{}{}
+	})
+	require.NoError(t, client.Open(ctx))
+
+	// cancelling the context of Open ends the connection without a
+	// disconnect, the input must not block either
+	cancel()
+	<-client.ws.Done()
+	require.Eventually(t, func() bool {
+		client.audioToAgent.mu.Lock()
+		defer client.audioToAgent.mu.Unlock()
+		return client.audioToAgent.closed
+	}, time.Second, time.Millisecond)
+
+	written := make(chan error, 1)
+	go func() {
+		_, err := client.InputAudio().Write(make([]byte, 3*4800))
+		written <- err
+	}()
+	select {
+	case err := <-written:
+		require.ErrorIs(t, err, ErrClosed)
+	case <-time.After(time.Second):
+		t.Fatal("write blocked")
+	}
+	require.Empty(t, disconnected)
+}
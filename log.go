@@ -0,0 +1,60 @@
+package openairt
+
+import (
+	"context"
+	"github.com/codewandler/openairt-go/internal/websocket"
+	"log/slog"
+)
+
+// LevelTrace is the level of the protocol trace: every websocket frame sent
+// and received. It is below slog.LevelDebug, so it has to be enabled
+// explicitly on the handler of the logger set with WithLogger.
+const LevelTrace = websocket.LevelTrace
+
+// log returns the logger of the client, with the session ID once known.
+func (c *Client) log() *slog.Logger {
+	return c.logger.Load()
+}
+
+// setSessionID adds the session ID to all log lines that follow.
+func (c *Client) setSessionID(id string) {
+	c.logger.Store(c.config.logger.With(slog.String("session_id", id)))
+	c.telemetry.setSessionID(id)
+}
+
+// logHandler hands the records to the current logger of the client, so the
+// loggers derived from it before the session was created, e.g. by the
+// websocket, log the session ID too.
+type logHandler struct {
+	c     *Client
+	wraps []func(slog.Handler) slog.Handler
+}
+
+func (h *logHandler) handler() slog.Handler {
+	handler := h.c.log().Handler()
+	for _, wrap := range h.wraps {
+		handler = wrap(handler)
+	}
+	return handler
+}
+
+func (h *logHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	// attributes and groups do not change the level
+	return h.c.log().Handler().Enabled(ctx, level)
+}
+
+func (h *logHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.handler().Handle(ctx, r)
+}
+
+func (h *logHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(handler slog.Handler) slog.Handler { return handler.WithAttrs(attrs) })
+}
+
+func (h *logHandler) WithGroup(name string) slog.Handler {
+	return h.with(func(handler slog.Handler) slog.Handler { return handler.WithGroup(name) })
+}
+
+func (h *logHandler) with(wrap func(slog.Handler) slog.Handler) slog.Handler {
+	return &logHandler{c: h.c, wraps: append(h.wraps[:len(h.wraps):len(h.wraps)], wrap)}
+}
@@ -0,0 +1,126 @@
+package openairt
+
+import (
+	"context"
+	"github.com/codewandler/openairt-go/openairttest"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordHandler keeps the records it handles.
+type recordHandler struct {
+	level slog.Level
+	attrs []slog.Attr
+
+	mu      *sync.Mutex
+	records *[]slog.Record
+}
+
+func newRecordHandler(level slog.Level) *recordHandler {
+	return &recordHandler{level: level, mu: &sync.Mutex{}, records: &[]slog.Record{}}
+}
+
+func (h *recordHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *recordHandler) Handle(_ context.Context, r slog.Record) error {
+	r = r.Clone()
+	r.AddAttrs(h.attrs...)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	*h.records = append(*h.records, r)
+	return nil
+}
+
+func (h *recordHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.attrs = append(append([]slog.Attr(nil), h.attrs...), attrs...)
+	return &c
+}
+
+func (h *recordHandler) WithGroup(string) slog.Handler {
+	return h
+}
+
+// find returns the first record with the message.
+func (h *recordHandler) find(msg string) (slog.Record, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, r := range *h.records {
+		if r.Message == msg {
+			return r, true
+		}
+	}
+	return slog.Record{}, false
+}
+
+func recordAttr(r slog.Record, key string) string {
+	var v string
+	r.Attrs(func(a slog.Attr) bool {
+		if a.Key == key {
+			v = a.Value.String()
+			return false
+		}
+		return true
+	})
+	return v
+}
+
+func TestLogger(t *testing.T) {
+	srv := openairttest.NewServer()
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	h := newRecordHandler(LevelTrace)
+	client := New(WithKey("test"), WithBaseURL(srv.URL), WithLogger(slog.New(h)))
+	require.NoError(t, client.Open(ctx))
+
+	require.NoError(t, srv.Send(map[string]any{
+		"type":     "response.created",
+		"event_id": "evt_1",
+		"response": map[string]any{"id": "resp_1", "status": "in_progress"},
+	}))
+
+	var r slog.Record
+	require.Eventually(t, func() bool {
+		var ok bool
+		r, ok = h.find("response created")
+		return ok
+	}, time.Second, 5*time.Millisecond)
+	require.Equal(t, "sess_test", recordAttr(r, "session_id"))
+	require.Equal(t, "resp_1", recordAttr(r, "response_id"))
+
+	// the protocol trace, with the session ID once known
+	r, ok := h.find("rcv: text")
+	require.True(t, ok)
+	require.True(t, strings.HasPrefix(recordAttr(r, "url"), srv.URL))
+	r, ok = h.find("snd: text")
+	require.True(t, ok)
+	require.Equal(t, "sess_test", recordAttr(r, "session_id"))
+}
+
+func TestLoggerTraceOptIn(t *testing.T) {
+	srv := openairttest.NewServer()
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	h := newRecordHandler(slog.LevelDebug)
+	client := New(WithKey("test"), WithBaseURL(srv.URL), WithLogger(slog.New(h)))
+	require.NoError(t, client.Open(ctx))
+
+	_, ok := h.find("rcv: text")
+	require.False(t, ok)
+	_, ok = h.find("snd: text")
+	require.False(t, ok)
+}
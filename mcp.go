@@ -0,0 +1,62 @@
+package openairt
+
+import (
+	"github.com/codewandler/openairt-go/events"
+	nanoid "github.com/matoous/go-nanoid/v2"
+)
+
+// OnMCPApprovalRequest sets the handler for approval requests of remote MCP
+// tools (see tool.TypeMCP). The handler must answer each request with
+// AnswerMCPApproval, otherwise the call does not run.
+func (c *Client) OnMCPApprovalRequest(h func(req events.ResponseDoneOutput)) {
+	c.onMCPApprove = h
+}
+
+// AnswerMCPApproval approves or rejects a pending mcp_approval_request. If
+// respond is true, a response is created so the model continues.
+func (c *Client) AnswerMCPApproval(requestID string, approve bool, reason string, respond bool) error {
+	id, _ := nanoid.New()
+	err := c.Send(events.ConversationItemCreateEvent{
+		BaseEvent: events.NewBaseEvent("conversation.item.create"),
+		Item: events.ConversationItem{
+			ID:                id,
+			Type:              "mcp_approval_response",
+			ApprovalRequestID: requestID,
+			Approve:           &approve,
+			Reason:            reason,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if respond {
+		return c.CreateResponse()
+	}
+
+	return nil
+}
+
+// handleMCPOutputs passes approval requests of a response to the handler.
+func (c *Client) handleMCPOutputs(outputs []events.ResponseDoneOutput) {
+	if c.onMCPApprove == nil {
+		return
+	}
+
+	var requests []events.ResponseDoneOutput
+	for _, o := range outputs {
+		if o.Type == "mcp_approval_request" {
+			requests = append(requests, o)
+		}
+	}
+	if len(requests) == 0 {
+		return
+	}
+
+	// the handler may block on a human, keep the read loop going
+	go func() {
+		for _, req := range requests {
+			c.onMCPApprove(req)
+		}
+	}()
+}
@@ -0,0 +1,70 @@
+package openairt
+
+import (
+	"context"
+	"github.com/codewandler/openairt-go/events"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestMCPApproval(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, srv := openTestClient(t)
+
+	requests := make(chan events.ResponseDoneOutput, 1)
+	client.OnMCPApprovalRequest(func(req events.ResponseDoneOutput) {
+		requests <- req
+	})
+
+	require.NoError(t, srv.Send(map[string]any{
+		"type":     "response.done",
+		"event_id": "evt_1",
+		"response": map[string]any{
+			"id":     "resp_1",
+			"status": "completed",
+			"output": []map[string]any{
+				{
+					"type":         "mcp_list_tools",
+					"id":           "item_1",
+					"server_label": "bank",
+					"tools":        []map[string]any{{"name": "transfer", "input_schema": map[string]any{"type": "object"}}},
+				},
+				{
+					"type":         "mcp_approval_request",
+					"id":           "apr_1",
+					"server_label": "bank",
+					"name":         "transfer",
+					"arguments":    `{"amount": 100}`,
+				},
+			},
+		},
+	}))
+
+	var req events.ResponseDoneOutput
+	select {
+	case req = <-requests:
+	case <-ctx.Done():
+		t.Fatal("no approval request")
+	}
+	require.Equal(t, "apr_1", req.ID)
+	require.Equal(t, "bank", req.ServerLabel)
+	require.Equal(t, "transfer", req.Name)
+
+	require.NoError(t, client.AnswerMCPApproval(req.ID, false, "not allowed", true))
+
+	evt, err := srv.Next(ctx, "conversation.item.create")
+	require.NoError(t, err)
+	var item events.ConversationItemCreateEvent
+	require.NoError(t, evt.Decode(&item))
+	require.Equal(t, "mcp_approval_response", item.Item.Type)
+	require.Equal(t, "apr_1", item.Item.ApprovalRequestID)
+	require.NotNil(t, item.Item.Approve)
+	require.False(t, *item.Item.Approve)
+	require.Equal(t, "not allowed", item.Item.Reason)
+
+	_, err = srv.Next(ctx, "response.create")
+	require.NoError(t, err)
+}
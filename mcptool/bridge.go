@@ -0,0 +1,250 @@
+// Package mcptool exposes the tools of an MCP (Model Context Protocol) server
+// to a realtime session.
+//
+//	bridge, err := mcptool.ConnectCommand(ctx, exec.Command("my-mcp-server"))
+//	...
+//	client := openairt.New(openairt.WithTools(bridge.Tools()...))
+//	client.OnToolCall(bridge.Call)
+package mcptool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/codewandler/openairt-go/tool"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// DefaultCallTimeout limits a single tools/call request made through Call.
+const DefaultCallTimeout = 30 * time.Second
+
+// Bridge is a connection to an MCP server.
+type Bridge struct {
+	session *mcp.ClientSession
+	tools   []tool.Tool
+}
+
+// Connect connects to an MCP server over the given transport and lists its
+// tools.
+func Connect(ctx context.Context, transport mcp.Transport) (*Bridge, error) {
+	client := mcp.NewClient(&mcp.Implementation{Name: "openairt-go", Version: "v1"}, nil)
+
+	session, err := client.Connect(ctx, transport, nil)
+	if err != nil {
+		return nil, fmt.Errorf("connect mcp server: %w", err)
+	}
+
+	b := &Bridge{session: session}
+	for t, err := range session.Tools(ctx, nil) {
+		if err != nil {
+			_ = session.Close()
+			return nil, fmt.Errorf("list mcp tools: %w", err)
+		}
+
+		b.tools = append(b.tools, tool.Tool{
+			Type:        "function",
+			Name:        t.Name,
+			Description: t.Description,
+			Parameters:  convertSchema(t.InputSchema),
+		})
+	}
+
+	return b, nil
+}
+
+// ConnectCommand starts cmd and talks to it over stdin/stdout.
+func ConnectCommand(ctx context.Context, cmd *exec.Cmd) (*Bridge, error) {
+	return Connect(ctx, &mcp.CommandTransport{Command: cmd})
+}
+
+// ConnectHTTP connects to a streamable HTTP MCP endpoint. httpClient may be
+// nil to use http.DefaultClient.
+func ConnectHTTP(ctx context.Context, endpoint string, httpClient *http.Client) (*Bridge, error) {
+	return Connect(ctx, &mcp.StreamableClientTransport{Endpoint: endpoint, HTTPClient: httpClient})
+}
+
+// Tools returns the tools of the server, ready to be passed to
+// openairt.WithTools.
+func (b *Bridge) Tools() []tool.Tool {
+	return b.tools
+}
+
+// Has reports whether the server provides a tool with the given name.
+func (b *Bridge) Has(name string) bool {
+	for _, t := range b.tools {
+		if t.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Call invokes a tool. Its signature matches openairt.Client.OnToolCall.
+func (b *Bridge) Call(name string, args map[string]any) (any, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultCallTimeout)
+	defer cancel()
+
+	return b.CallContext(ctx, name, args)
+}
+
+// CallContext invokes a tool via tools/call and converts its result. A result
+// flagged as error is returned as error, so the model sees it as such.
+func (b *Bridge) CallContext(ctx context.Context, name string, args map[string]any) (any, error) {
+	res, err := b.session.CallTool(ctx, &mcp.CallToolParams{
+		Name:      name,
+		Arguments: args,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("call mcp tool %s: %w", name, err)
+	}
+
+	return convertResult(res)
+}
+
+// Close closes the session. For command transports this stops the process.
+func (b *Bridge) Close() error {
+	return b.session.Close()
+}
+
+func convertResult(res *mcp.CallToolResult) (any, error) {
+	var (
+		texts    []string
+		contents []any
+	)
+	for _, c := range res.Content {
+		switch c := c.(type) {
+		case *mcp.TextContent:
+			texts = append(texts, c.Text)
+			contents = append(contents, c.Text)
+		case *mcp.ImageContent:
+			contents = append(contents, map[string]any{"type": "image", "mime_type": c.MIMEType})
+		case *mcp.AudioContent:
+			contents = append(contents, map[string]any{"type": "audio", "mime_type": c.MIMEType})
+		case *mcp.ResourceLink:
+			contents = append(contents, map[string]any{"type": "resource_link", "uri": c.URI, "name": c.Name})
+		case *mcp.EmbeddedResource:
+			if c.Resource != nil {
+				contents = append(contents, map[string]any{"type": "resource", "uri": c.Resource.URI, "text": c.Resource.Text})
+			}
+		}
+	}
+
+	if res.IsError {
+		if len(texts) == 0 {
+			return nil, errors.New("tool call failed")
+		}
+		return nil, errors.New(strings.Join(texts, "\n"))
+	}
+
+	if res.StructuredContent != nil {
+		return res.StructuredContent, nil
+	}
+
+	switch len(contents) {
+	case 0:
+		return nil, nil
+	case 1:
+		return contents[0], nil
+	default:
+		return contents, nil
+	}
+}
+
+// convertSchema maps a JSON schema to tool parameters. Only the subset of
+// JSON schema understood by the realtime API is kept.
+func convertSchema(schema any) tool.Parameters {
+	m := toMap(schema)
+
+	params := tool.Parameters{
+		Type:       "object",
+		Properties: tool.Properties{},
+		Required:   toStrings(m["required"]),
+	}
+	if params.Required == nil {
+		params.Required = []string{}
+	}
+
+	for name, p := range toMap(m["properties"]) {
+		params.Properties[name] = convertProperty(toMap(p))
+	}
+
+	return params
+}
+
+func convertProperty(m map[string]any) tool.Property {
+	p := tool.Property{
+		Type:     schemaType(m),
+		Required: toStrings(m["required"]),
+	}
+	p.Description, _ = m["description"].(string)
+	p.Enum, _ = m["enum"].([]any)
+
+	if items, ok := m["items"]; ok {
+		item := convertProperty(toMap(items))
+		p.Items = &item
+	}
+
+	if props := toMap(m["properties"]); len(props) > 0 {
+		p.Properties = tool.Properties{}
+		for name, sub := range props {
+			p.Properties[name] = convertProperty(toMap(sub))
+		}
+	}
+
+	return p
+}
+
+// schemaType returns the type of schema, picking the first non-null type of
+// a union.
+func schemaType(m map[string]any) string {
+	switch t := m["type"].(type) {
+	case string:
+		return t
+	case []any:
+		for _, x := range t {
+			if s, ok := x.(string); ok && s != "null" {
+				return s
+			}
+		}
+	}
+
+	switch {
+	case m["properties"] != nil:
+		return "object"
+	case m["items"] != nil:
+		return "array"
+	default:
+		return "string"
+	}
+}
+
+func toMap(v any) map[string]any {
+	if m, ok := v.(map[string]any); ok {
+		return m
+	}
+
+	// schemas of local servers may be typed, normalize them via json
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var m map[string]any
+	_ = json.Unmarshal(data, &m)
+	return m
+}
+
+func toStrings(v any) []string {
+	list, _ := v.([]any)
+	var out []string
+	for _, x := range list {
+		if s, ok := x.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,126 @@
+package mcptool
+
+import (
+	"context"
+	"errors"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+const serverEnv = "MCPTOOL_TEST_SERVER"
+
+// TestMain turns the test binary into an MCP stdio server when started by
+// the tests below.
+func TestMain(m *testing.M) {
+	if os.Getenv(serverEnv) == "1" {
+		if err := newTestServer().Run(context.Background(), &mcp.StdioTransport{}); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+type addInput struct {
+	A int `json:"a" jsonschema:"first summand"`
+	B int `json:"b" jsonschema:"second summand"`
+}
+
+type addOutput struct {
+	Sum int `json:"sum"`
+}
+
+type searchInput struct {
+	Query string   `json:"query"`
+	Tags  []string `json:"tags,omitempty"`
+}
+
+func newTestServer() *mcp.Server {
+	s := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "v1"}, nil)
+
+	mcp.AddTool(s, &mcp.Tool{Name: "add", Description: "Add two numbers"}, func(ctx context.Context, req *mcp.CallToolRequest, in addInput) (*mcp.CallToolResult, addOutput, error) {
+		return nil, addOutput{Sum: in.A + in.B}, nil
+	})
+	mcp.AddTool(s, &mcp.Tool{Name: "search", Description: "Search"}, func(ctx context.Context, req *mcp.CallToolRequest, in searchInput) (*mcp.CallToolResult, any, error) {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: "found: " + in.Query}},
+		}, nil, nil
+	})
+	mcp.AddTool(s, &mcp.Tool{Name: "fail", Description: "Always fails"}, func(ctx context.Context, req *mcp.CallToolRequest, in struct{}) (*mcp.CallToolResult, any, error) {
+		return nil, nil, errors.New("backend unavailable")
+	})
+
+	return s
+}
+
+func testBridge(t *testing.T, b *Bridge) {
+	t.Helper()
+
+	require.True(t, b.Has("add"))
+	require.False(t, b.Has("missing"))
+
+	tools := map[string]int{}
+	for i, x := range b.Tools() {
+		tools[x.Name] = i
+	}
+	require.Len(t, tools, 3)
+
+	add := b.Tools()[tools["add"]]
+	require.Equal(t, "function", add.Type)
+	require.Equal(t, "Add two numbers", add.Description)
+	require.Equal(t, "object", add.Parameters.Type)
+	require.Equal(t, "integer", add.Parameters.Properties["a"].Type)
+	require.Equal(t, "first summand", add.Parameters.Properties["a"].Description)
+	require.ElementsMatch(t, []string{"a", "b"}, add.Parameters.Required)
+
+	search := b.Tools()[tools["search"]]
+	require.Equal(t, "array", search.Parameters.Properties["tags"].Type)
+	require.Equal(t, "string", search.Parameters.Properties["tags"].Items.Type)
+
+	res, err := b.Call("add", map[string]any{"a": 2, "b": 3})
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"sum": float64(5)}, res)
+
+	res, err = b.Call("search", map[string]any{"query": "invoices"})
+	require.NoError(t, err)
+	require.Equal(t, "found: invoices", res)
+
+	_, err = b.Call("fail", map[string]any{})
+	require.EqualError(t, err, "backend unavailable")
+}
+
+func TestBridgeCommand(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cmd := exec.Command(os.Args[0])
+	cmd.Env = append(os.Environ(), serverEnv+"=1")
+
+	b, err := ConnectCommand(ctx, cmd)
+	require.NoError(t, err)
+	defer b.Close()
+
+	testBridge(t, b)
+}
+
+func TestBridgeHTTP(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	server := newTestServer()
+	srv := httptest.NewServer(mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return server }, nil))
+	defer srv.Close()
+
+	b, err := ConnectHTTP(ctx, srv.URL, nil)
+	require.NoError(t, err)
+	defer b.Close()
+
+	testBridge(t, b)
+}
@@ -0,0 +1,64 @@
+package openairttest
+
+import (
+	"context"
+	"fmt"
+	"github.com/codewandler/openairt-go/wirelog"
+	"slices"
+	"time"
+)
+
+// ReplayOptions configure Server.Replay.
+type ReplayOptions struct {
+	// Timing keeps the recorded delays between the frames sent to the
+	// client instead of sending them as fast as possible.
+	Timing bool
+	// Skip lists the types of recorded client frames Replay does not wait
+	// for. Empty skips session.update and input_audio_buffer.append, whose
+	// timing depends on the audio source.
+	Skip []string
+}
+
+// Replay plays the server side of a recording, see package wirelog, to the
+// connected client. Recorded server frames are sent again. For recorded
+// client frames, Replay waits until the client sends an event of the same
+// type, so the recorded order is kept. The handshake is answered by the
+// Server itself, its recorded frames are skipped.
+func (s *Server) Replay(ctx context.Context, frames []wirelog.Frame, opts ReplayOptions) error {
+	skip := opts.Skip
+	if len(skip) == 0 {
+		skip = []string{"session.update", "input_audio_buffer.append"}
+	}
+
+	var last time.Time
+	for i, f := range frames {
+		switch f.Dir {
+		case wirelog.In:
+			if f.Type == "session.created" || f.Type == "session.updated" {
+				continue
+			}
+
+			if opts.Timing && !last.IsZero() {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(f.Time.Sub(last)):
+				}
+			}
+			last = f.Time
+
+			if err := s.sendRaw(f.Data); err != nil {
+				return fmt.Errorf("frame %d: %w", i, err)
+			}
+		case wirelog.Out:
+			if slices.Contains(skip, f.Type) {
+				continue
+			}
+			if _, err := s.Next(ctx, f.Type); err != nil {
+				return fmt.Errorf("frame %d: %w", i, err)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,163 @@
+// Package openairttest provides a scriptable realtime API server for tests.
+package openairttest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gobwas/ws"
+	"github.com/gobwas/ws/wsutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Event is a client event received by the Server.
+type Event struct {
+	Type string
+	Data json.RawMessage
+}
+
+// Decode unmarshals the raw event into v.
+func (e Event) Decode(v any) error {
+	return json.Unmarshal(e.Data, v)
+}
+
+// Server is a minimal stand-in for the realtime API. It accepts one client
+// at a time, answers the session handshake and records every event the
+// client sends.
+type Server struct {
+	// URL is the websocket base url to pass to openairt.WithBaseURL.
+	URL string
+
+	srv      *httptest.Server
+	received chan Event
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewServer starts a new Server listening on a local port.
+func NewServer() *Server {
+	s := &Server{
+		received: make(chan Event, 1000),
+	}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.handle))
+	s.URL = "ws" + strings.TrimPrefix(s.srv.URL, "http")
+	return s
+}
+
+// Close shuts down the server and drops the current connection.
+func (s *Server) Close() {
+	s.mu.Lock()
+	if s.conn != nil {
+		_ = s.conn.Close()
+	}
+	s.mu.Unlock()
+	s.srv.Close()
+}
+
+// Send sends evt to the connected client.
+func (s *Server) Send(evt any) error {
+	data, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+
+	return s.sendRaw(data)
+}
+
+func (s *Server) sendRaw(data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return errors.New("no client connected")
+	}
+
+	return wsutil.WriteServerText(s.conn, data)
+}
+
+// Next returns the next event received from the client with the given type.
+// Events of other types are discarded.
+func (s *Server) Next(ctx context.Context, eventType string) (Event, error) {
+	return s.NextOf(ctx, eventType)
+}
+
+// NextOf returns the next event received from the client with one of the
+// given types. Events of other types are discarded.
+func (s *Server) NextOf(ctx context.Context, eventTypes ...string) (Event, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return Event{}, fmt.Errorf("waiting for %s: %w", strings.Join(eventTypes, ", "), ctx.Err())
+		case evt := <-s.received:
+			if slices.Contains(eventTypes, evt.Type) {
+				return evt, nil
+			}
+		}
+	}
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	conn, _, _, err := ws.UpgradeHTTP(r, w)
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	s.conn = conn
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		if s.conn == conn {
+			s.conn = nil
+		}
+		s.mu.Unlock()
+		_ = conn.Close()
+	}()
+
+	if err := s.Send(map[string]any{
+		"type":     "session.created",
+		"event_id": "evt_session_created",
+		"session":  map[string]any{"id": "sess_test", "object": "realtime.session"},
+	}); err != nil {
+		return
+	}
+
+	for {
+		data, op, err := wsutil.ReadClientData(conn)
+		if err != nil {
+			return
+		}
+		if op != ws.OpText {
+			continue
+		}
+
+		var x struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(data, &x); err != nil {
+			continue
+		}
+
+		if x.Type == "session.update" {
+			if err := s.Send(map[string]any{
+				"type":     "session.updated",
+				"event_id": "evt_session_updated",
+				"session":  map[string]any{"id": "sess_test", "object": "realtime.session"},
+			}); err != nil {
+				return
+			}
+		}
+
+		select {
+		case s.received <- Event{Type: x.Type, Data: data}:
+		default:
+		}
+	}
+}
@@ -1,10 +1,23 @@
 package openairt
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"github.com/codewandler/openairt-go/events"
 	"github.com/codewandler/openairt-go/tool"
+	"github.com/codewandler/openairt-go/wirelog"
+	"go.opentelemetry.io/otel/metric"
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/trace"
+	tracenoop "go.opentelemetry.io/otel/trace/noop"
 	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
+	"time"
 )
 
 const (
@@ -13,22 +26,83 @@ const (
 )
 
 type clientConfig struct {
-	model       string
-	apiKey      string
-	instruction string
-	language    string
-	voice       string
-	temperature float64
-	speed       float64
-	sampleRate  int
-	logger      *slog.Logger
-	tools       []tool.Tool
+	baseURL        string
+	model          string
+	apiKey         string
+	instruction    string
+	language       string
+	voice          string
+	temperature    float64
+	speed          float64
+	sampleRate     int
+	inFormat       events.AudioFormat
+	outFormat      events.AudioFormat
+	recorder       *Recorder
+	playout        *PlayoutConfig
+	vad            *VADConfig
+	overflow       OverflowPolicy
+	inputBuffer    time.Duration
+	inputChunk     time.Duration
+	streams        bool
+	prices         PriceTable
+	budget         float64
+	limiter        *RateLimiter
+	turnWindow     int
+	wire           *wirelog.Recorder
+	keepalive      KeepaliveConfig
+	netDial        func(ctx context.Context, network, addr string) (net.Conn, error)
+	proxy          func(req *http.Request) (*url.URL, error)
+	tlsConfig      *tls.Config
+	proxyTLSConfig *tls.Config
+	compression    *CompressionConfig
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+	logger         *slog.Logger
+	tools          []tool.Tool
+	approver       tool.Approver
+	confirmTimeout time.Duration
+	toolOutput     int
+	envelope       tool.EnvelopeFunc
+}
+
+// audioRate returns sample rate and sample size of the audio exchanged via
+// Client.Audio for an audio format. Only pcm16 is converted to the configured
+// sample rate, G.711 is passed through as is.
+func (c *clientConfig) audioRate(f events.AudioFormat) (rate int, bytesPerSample int) {
+	if f == events.AudioFormatPCM16 {
+		return c.sampleRate, 2
+	}
+	return f.SampleRate(), f.BytesPerSample()
+}
+
+func (c *clientConfig) findTool(name string) *tool.Tool {
+	for i := range c.tools {
+		if c.tools[i].Name == name {
+			return &c.tools[i]
+		}
+	}
+	return nil
 }
 
 func (c *clientConfig) validate() error {
 	if c.apiKey == "" {
 		return fmt.Errorf("missing api key")
 	}
+	if c.sampleRate <= 0 {
+		return fmt.Errorf("invalid sample rate: %d", c.sampleRate)
+	}
+	if _, ok := c.prices.Lookup(c.model); c.budget > 0 && !ok {
+		return fmt.Errorf("budget requires prices for model %s", c.model)
+	}
+	if c.confirmTimeout <= 0 {
+		return fmt.Errorf("invalid tool confirmation timeout: %s", c.confirmTimeout)
+	}
+	if c.toolOutput > 0 && c.toolOutput < len(truncatedMarker) {
+		return fmt.Errorf("tool output limit too small: %d", c.toolOutput)
+	}
+	if c.playout != nil && c.streams {
+		return fmt.Errorf("output streams and playout are exclusive")
+	}
 	return nil
 }
 
@@ -40,6 +114,42 @@ func WithTools(tools ...tool.Tool) ClientOption {
 	}
 }
 
+// WithToolApprover sets an approval stage that runs before every tool call.
+// Calls are only passed to the OnToolCall handler once they are approved.
+func WithToolApprover(approver tool.Approver) ClientOption {
+	return func(config *clientConfig) {
+		config.approver = approver
+	}
+}
+
+// WithToolConfirmationTimeout sets how long a call waits for the user to
+// confirm it, see tool.Confirm. Unanswered calls are dropped afterward.
+func WithToolConfirmationTimeout(timeout time.Duration) ClientOption {
+	return func(config *clientConfig) {
+		config.confirmTimeout = timeout
+	}
+}
+
+// WithToolOutputLimit limits the size of a tool call output in bytes. Larger
+// outputs are truncated, a value <= 0 disables the limit. The limit must
+// leave room for {"truncated":true}, the smallest truncated output.
+func WithToolOutputLimit(maxBytes int) ClientOption {
+	return func(config *clientConfig) {
+		config.toolOutput = maxBytes
+	}
+}
+
+// WithToolEnvelope customises the output the model sees for a tool call,
+// see tool.DefaultEnvelope. A nil envelope restores the default.
+func WithToolEnvelope(envelope tool.EnvelopeFunc) ClientOption {
+	return func(config *clientConfig) {
+		if envelope == nil {
+			envelope = tool.DefaultEnvelope
+		}
+		config.envelope = envelope
+	}
+}
+
 func WithVoice(voice string) ClientOption {
 	return func(config *clientConfig) {
 		config.voice = voice
@@ -52,12 +162,162 @@ func WithSpeed(speed float64) ClientOption {
 	}
 }
 
+// WithSampleRate sets the sample rate of pcm16 audio exchanged via
+// Client.Audio. The client converts it from and to the 24 kHz of the API.
 func WithSampleRate(sr int) ClientOption {
 	return func(config *clientConfig) {
 		config.sampleRate = sr
 	}
 }
 
+// WithInputAudioFormat sets the format of the audio written to Client.Audio.
+// G.711 formats are sent as is at 8 kHz, see package audio/g711 to convert
+// them from PCM16.
+func WithInputAudioFormat(format events.AudioFormat) ClientOption {
+	return func(config *clientConfig) {
+		config.inFormat = format
+	}
+}
+
+// WithOutputAudioFormat sets the format of the audio read from Client.Audio.
+func WithOutputAudioFormat(format events.AudioFormat) ClientOption {
+	return func(config *clientConfig) {
+		config.outFormat = format
+	}
+}
+
+// WithAudioFormat sets input and output audio format.
+func WithAudioFormat(format events.AudioFormat) ClientOption {
+	return WithOptions(
+		WithInputAudioFormat(format),
+		WithOutputAudioFormat(format),
+	)
+}
+
+// WithRecorder records the call. Close the Recorder when the call ended.
+func WithRecorder(r *Recorder) ClientOption {
+	return func(config *clientConfig) {
+		config.recorder = r
+	}
+}
+
+// WithPlayout releases response audio to Client.Audio at real time in frames
+// instead of as fast as it arrives. While a response plays, missing audio is
+// replaced by silence. See Client.Playhead and Client.FlushPlayout.
+func WithPlayout(config PlayoutConfig) ClientOption {
+	return func(c *clientConfig) {
+		c.playout = &config
+	}
+}
+
+// WithOutputStreams delivers response audio as one OutputStream per item via
+// Client.OutputStreams instead of via Client.OutputAudio. It cannot be
+// combined with WithPlayout.
+func WithOutputStreams() ClientOption {
+	return func(c *clientConfig) {
+		c.streams = true
+	}
+}
+
+// WithVAD runs a local voice activity detection on the audio written to
+// Client.Audio, see VADConfig.
+func WithVAD(config VADConfig) ClientOption {
+	return func(c *clientConfig) {
+		c.vad = &config
+	}
+}
+
+// WithInputOverflow sets what happens to input audio when the input buffer
+// is full, OverflowBlock by default. See Client.InputStats for the amount of
+// dropped audio.
+func WithInputOverflow(policy OverflowPolicy) ClientOption {
+	return func(config *clientConfig) {
+		config.overflow = policy
+	}
+}
+
+// WithInputBuffer sets the amount of input audio buffered until it is sent,
+// one second by default.
+func WithInputBuffer(d time.Duration) ClientOption {
+	return func(config *clientConfig) {
+		config.inputBuffer = d
+	}
+}
+
+// WithInputChunk sets the duration of the audio sent with each
+// input_audio_buffer.append event, 20ms by default. Longer chunks cut the
+// overhead per event but add latency.
+func WithInputChunk(d time.Duration) ClientOption {
+	return func(config *clientConfig) {
+		config.inputChunk = d
+	}
+}
+
+// WithPrices sets the prices used to estimate the cost in Client.Usage,
+// DefaultPrices by default.
+func WithPrices(prices PriceTable) ClientOption {
+	return func(config *clientConfig) {
+		config.prices = prices
+	}
+}
+
+// WithBudget limits the estimated cost of the session in USD. Once the usage
+// exceeds it, responses in progress are cancelled and new ones fail with
+// ErrBudgetExceeded. It requires prices for the model, see WithPrices.
+func WithBudget(usd float64) ClientOption {
+	return func(config *clientConfig) {
+		config.budget = usd
+	}
+}
+
+// WithRateLimiter makes CreateResponse and UserInput wait for the limiter,
+// which learns the limits from the rate_limits.updated events of all clients
+// sharing it.
+func WithRateLimiter(limiter *RateLimiter) ClientOption {
+	return func(config *clientConfig) {
+		config.limiter = limiter
+	}
+}
+
+// WithTurnWindow sets the number of recent turns Client.TurnLatency is
+// computed over, 100 by default.
+func WithTurnWindow(turns int) ClientOption {
+	return func(config *clientConfig) {
+		config.turnWindow = turns
+	}
+}
+
+// WithWireRecorder records every websocket text frame of the session, see
+// package wirelog. Recordings can be replayed with openairttest.
+func WithWireRecorder(r *wirelog.Recorder) ClientOption {
+	return func(config *clientConfig) {
+		config.wire = r
+	}
+}
+
+// WithKeepalive sets how dead connections are detected, DefaultKeepalive by
+// default. A lost connection is reported to Client.OnDisconnect.
+func WithKeepalive(config KeepaliveConfig) ClientOption {
+	return func(c *clientConfig) {
+		c.keepalive = config
+	}
+}
+
+// WithTracerProvider records a span per session, response and tool call.
+func WithTracerProvider(tp trace.TracerProvider) ClientOption {
+	return func(config *clientConfig) {
+		config.tracerProvider = tp
+	}
+}
+
+// WithMeterProvider records the time to first audio, reconnects, dropped
+// input audio, tool call durations and token usage.
+func WithMeterProvider(mp metric.MeterProvider) ClientOption {
+	return func(config *clientConfig) {
+		config.meterProvider = mp
+	}
+}
+
 func WithLogger(logger *slog.Logger) ClientOption {
 	return func(o *clientConfig) {
 		o.logger = logger
@@ -74,6 +334,83 @@ func WithTemperature(temperature float64) ClientOption {
 	}
 }
 
+// WithBaseURL sets the realtime endpoint, e.g. to point the client at a proxy
+// or an openairttest.Server.
+func WithBaseURL(url string) ClientOption {
+	return func(o *clientConfig) {
+		o.baseURL = url
+	}
+}
+
+// WithProxy sets the proxy the connection is tunnelled through with a
+// CONNECT request, http.ProxyFromEnvironment by default, so HTTPS_PROXY and
+// NO_PROXY apply. Nil connects directly.
+func WithProxy(proxy func(req *http.Request) (*url.URL, error)) ClientOption {
+	return func(o *clientConfig) {
+		o.proxy = proxy
+	}
+}
+
+// WithNetDial sets the function dialing the TCP connection to the API or the
+// proxy.
+func WithNetDial(dial func(ctx context.Context, network, addr string) (net.Conn, error)) ClientOption {
+	return func(o *clientConfig) {
+		o.netDial = dial
+	}
+}
+
+// WithTLSConfig sets the TLS configuration of the connection. The config is
+// not modified by WithRootCAs and WithClientCertificate.
+func WithTLSConfig(config *tls.Config) ClientOption {
+	return func(o *clientConfig) {
+		o.tlsConfig = config
+	}
+}
+
+// WithProxyTLSConfig sets the TLS configuration of the connection to an
+// https proxy. By default the proxy is verified with the system roots, the
+// config of WithTLSConfig, WithRootCAs and WithClientCertificate only applies
+// to the API.
+func WithProxyTLSConfig(config *tls.Config) ClientOption {
+	return func(o *clientConfig) {
+		o.proxyTLSConfig = config
+	}
+}
+
+// WithRootCAs sets the certificate authorities the server certificate is
+// verified with instead of the ones of the system.
+func WithRootCAs(pool *x509.CertPool) ClientOption {
+	return func(o *clientConfig) {
+		o.tlsConfig = o.tlsConfig.Clone()
+		if o.tlsConfig == nil {
+			o.tlsConfig = &tls.Config{}
+		}
+		o.tlsConfig.RootCAs = pool
+	}
+}
+
+// WithClientCertificate adds a certificate presented to servers requiring
+// mutual TLS.
+func WithClientCertificate(cert tls.Certificate) ClientOption {
+	return func(o *clientConfig) {
+		o.tlsConfig = o.tlsConfig.Clone()
+		if o.tlsConfig == nil {
+			o.tlsConfig = &tls.Config{}
+		}
+		certs := o.tlsConfig.Certificates
+		o.tlsConfig.Certificates = append(certs[:len(certs):len(certs)], cert)
+	}
+}
+
+// WithCompression offers the server to compress the messages with
+// permessage-deflate, which mostly shrinks the base64 audio sent. If the
+// server declines, messages are sent uncompressed, see Client.Compressed.
+func WithCompression(config CompressionConfig) ClientOption {
+	return func(o *clientConfig) {
+		o.compression = &config
+	}
+}
+
 func WithModel(model string) ClientOption {
 	return func(o *clientConfig) {
 		o.model = model
@@ -113,7 +450,19 @@ func withDefaults() ClientOption {
 		WithInstruction("You are a helpcenter agent and help the user."),
 		WithTemperature(0.8),
 		WithSampleRate(24_000),
+		WithAudioFormat(events.AudioFormatPCM16),
+		WithInputBuffer(time.Second),
+		WithPrices(DefaultPrices),
+		WithTurnWindow(100),
+		WithKeepalive(DefaultKeepalive),
+		WithProxy(http.ProxyFromEnvironment),
+		WithTracerProvider(tracenoop.NewTracerProvider()),
+		WithMeterProvider(metricnoop.NewMeterProvider()),
+		WithInputChunk(20*time.Millisecond),
 		WithSpeed(1.1),
+		WithBaseURL("wss://api.openai.com/v1/realtime"),
+		WithToolEnvelope(tool.DefaultEnvelope),
+		WithToolConfirmationTimeout(2*time.Minute),
 		WithModel("gpt-4o-realtime-preview-2025-06-03"),
 		WithEnvKey(ApiKeyEnvVarNameShort, ApiKeyEnvVarNameLong),
 	)
@@ -0,0 +1,266 @@
+package openairt
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+)
+
+// ErrInterrupted is returned by the readers of response audio when audio not
+// read yet was dropped because the user interrupted the response. Reading can
+// continue with the audio of the next response.
+var ErrInterrupted = errors.New("response audio interrupted")
+
+// InputAudio returns the writer for user audio, in the format set with
+// WithInputAudioFormat; pcm16 at the sample rate set with WithSampleRate.
+// Closing it sends the audio buffered so far and ends the input. Once the
+// connection ended, however it ended, writes fail with ErrClosed until the
+// next Open.
+func (c *Client) InputAudio() io.WriteCloser {
+	return &inputWriter{c: c}
+}
+
+// OutputAudio returns the reader for response audio, in the format set with
+// WithOutputAudioFormat; pcm16 at the sample rate set with WithSampleRate.
+// Read returns ErrInterrupted once after an interruption dropped audio.
+// With WithPlayout the fade out of the interrupted audio follows. Without
+// WithOutputStreams there is no separation between responses.
+func (c *Client) OutputAudio() io.Reader {
+	return &outputReader{c: c}
+}
+
+// Audio combines InputAudio and OutputAudio.
+func (c *Client) Audio() io.ReadWriter {
+	return &readWriter{
+		Reader: c.OutputAudio(),
+		Writer: c.InputAudio(),
+	}
+}
+
+type inputWriter struct {
+	c *Client
+}
+
+func (w *inputWriter) Write(p []byte) (int, error) {
+	n, err := w.c.audioToAgent.Write(p)
+	if n > 0 && w.c.config.recorder != nil {
+		w.c.config.recorder.add(recorderUser, p[:n])
+	}
+	return n, err
+}
+
+func (w *inputWriter) Close() error {
+	return w.c.audioToAgent.Close()
+}
+
+type outputReader struct {
+	c *Client
+}
+
+func (r *outputReader) Read(p []byte) (int, error) {
+	c := r.c
+
+	// the ring buffer reports a reset only to readers blocked at the time
+	if seen := c.outputSeen.Load(); seen != c.interrupts.Load() {
+		c.outputSeen.Store(c.interrupts.Load())
+		return 0, ErrInterrupted
+	}
+
+	n, err := c.audioToUser.Read(p)
+	if err != nil {
+		if c.outputSeen.Load() != c.interrupts.Load() {
+			c.outputSeen.Store(c.interrupts.Load())
+			return 0, ErrInterrupted
+		}
+		return n, err
+	}
+
+	if n > 0 && c.config.recorder != nil {
+		c.config.recorder.add(recorderAgent, p[:n])
+	}
+	return n, nil
+}
+
+// resetOutput drops the response audio not read yet. Readers only learn
+// about it if there was audio to drop.
+func (c *Client) resetOutput() {
+	if c.audioToUser.IsEmpty() {
+		return
+	}
+	c.interrupts.Add(1)
+	c.audioToUser.Reset()
+}
+
+// OutputStream is the audio of one item of a response, see
+// WithOutputStreams. Read returns io.EOF at the end of the audio and
+// ErrInterrupted if the user interrupted the response.
+type OutputStream struct {
+	ResponseID string
+	ItemID     string
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	data     []byte
+	err      error
+	recorder *Recorder
+}
+
+func newOutputStream(responseID, itemID string, recorder *Recorder) *OutputStream {
+	s := &OutputStream{
+		ResponseID: responseID,
+		ItemID:     itemID,
+		recorder:   recorder,
+	}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+// Read reads audio of the item, blocking until more arrives. The audio
+// buffered before the end is read first, unless the stream was interrupted.
+func (s *OutputStream) Read(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for len(s.data) == 0 && s.err == nil {
+		s.cond.Wait()
+	}
+	if len(s.data) == 0 {
+		return 0, s.err
+	}
+
+	n := copy(p, s.data)
+	s.data = s.data[n:]
+	if s.recorder != nil {
+		s.recorder.add(recorderAgent, p[:n])
+	}
+	return n, nil
+}
+
+func (s *OutputStream) write(data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return
+	}
+	s.data = append(s.data, data...)
+	s.cond.Broadcast()
+}
+
+// end closes the stream with err, ErrInterrupted drops the buffered audio.
+func (s *OutputStream) end(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return
+	}
+	s.err = err
+	if errors.Is(err, ErrInterrupted) {
+		s.data = nil
+	}
+	s.cond.Broadcast()
+}
+
+// outputStreams routes response audio to one OutputStream per item.
+type outputStreams struct {
+	mu       sync.Mutex
+	ch       chan *OutputStream
+	open     map[string]*OutputStream
+	dropped  map[string]bool // interrupted items, late audio is ignored
+	closed   bool
+	recorder *Recorder
+	log      func() *slog.Logger
+}
+
+func newOutputStreams(recorder *Recorder, log func() *slog.Logger) *outputStreams {
+	return &outputStreams{
+		ch:       make(chan *OutputStream, 64),
+		open:     map[string]*OutputStream{},
+		dropped:  map[string]bool{},
+		recorder: recorder,
+		log:      log,
+	}
+}
+
+// write appends audio to the stream of an item, starting it with the first
+// audio. It returns false if the audio was dropped.
+func (o *outputStreams) write(responseID, itemID string, data []byte) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.closed || o.dropped[itemID] {
+		return false
+	}
+
+	s, ok := o.open[itemID]
+	if !ok {
+		s = newOutputStream(responseID, itemID, o.recorder)
+		select {
+		case o.ch <- s:
+		default:
+			o.log().Warn("output stream dropped, OutputStreams is not read", slog.String("response_id", responseID), slog.String("item_id", itemID))
+			o.dropped[itemID] = true
+			return false
+		}
+		o.open[itemID] = s
+	}
+
+	s.write(data)
+	return true
+}
+
+// audioDone ends the stream of an item.
+func (o *outputStreams) audioDone(itemID string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if s, ok := o.open[itemID]; ok {
+		s.end(io.EOF)
+		delete(o.open, itemID)
+	}
+	// no more audio of the item will arrive
+	delete(o.dropped, itemID)
+}
+
+// responseDone forgets the dropped items of a finished response, a
+// cancelled response may end without their audio being done.
+func (o *outputStreams) responseDone(itemIDs []string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for _, id := range itemIDs {
+		delete(o.dropped, id)
+	}
+}
+
+// close ends the open streams with ErrClosed and closes the channel of
+// OutputStreams.
+func (o *outputStreams) close() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.closed {
+		return
+	}
+	o.closed = true
+	for _, s := range o.open {
+		s.end(ErrClosed)
+	}
+	clear(o.open)
+	clear(o.dropped)
+	close(o.ch)
+}
+
+// interrupt ends all open streams with ErrInterrupted.
+func (o *outputStreams) interrupt() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for id, s := range o.open {
+		s.end(ErrInterrupted)
+		o.dropped[id] = true
+	}
+	clear(o.open)
+}
@@ -0,0 +1,139 @@
+package openairt
+
+import (
+	"context"
+	"encoding/base64"
+	"github.com/codewandler/openairt-go/events"
+	"github.com/stretchr/testify/require"
+	"io"
+	"testing"
+	"time"
+)
+
+func sendAudioDelta(t *testing.T, srv interface{ Send(any) error }, itemID string, data []byte) {
+	t.Helper()
+
+	require.NoError(t, srv.Send(map[string]any{
+		"type":        "response.audio.delta",
+		"event_id":    "evt_delta",
+		"response_id": "resp_1",
+		"item_id":     itemID,
+		"delta":       base64.StdEncoding.EncodeToString(data),
+	}))
+}
+
+func TestOutputAudioInterrupted(t *testing.T) {
+	client, srv := openTestClient(t, WithAudioFormat(events.AudioFormatG711ULaw))
+	out := client.OutputAudio()
+
+	sendAudioDelta(t, srv, "item_1", make([]byte, 160))
+	require.NoError(t, srv.Send(map[string]any{
+		"type":     "input_audio_buffer.speech_started",
+		"event_id": "evt_2",
+		"item_id":  "item_user",
+	}))
+	require.Eventually(t, func() bool {
+		return client.interrupts.Load() == 1
+	}, time.Second, 5*time.Millisecond)
+
+	buf := make([]byte, 320)
+	_, err := out.Read(buf)
+	require.ErrorIs(t, err, ErrInterrupted)
+
+	// an interruption without audio to drop goes unnoticed
+	client.interruptOutput("")
+	require.EqualValues(t, 1, client.interrupts.Load())
+
+	// the next response is read as usual
+	sendAudioDelta(t, srv, "item_2", make([]byte, 160))
+	n, err := out.Read(buf)
+	require.NoError(t, err)
+	require.Equal(t, 160, n)
+}
+
+func TestOutputStreams(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, srv := openTestClient(t,
+		WithAudioFormat(events.AudioFormatG711ULaw),
+		WithOutputStreams(),
+	)
+
+	next := func() *OutputStream {
+		select {
+		case s := <-client.OutputStreams():
+			return s
+		case <-ctx.Done():
+			t.Fatal("no output stream")
+			return nil
+		}
+	}
+
+	sendAudioDelta(t, srv, "item_1", make([]byte, 160))
+	sendAudioDelta(t, srv, "item_1", make([]byte, 160))
+	require.NoError(t, srv.Send(map[string]any{
+		"type":     "response.audio.done",
+		"event_id": "evt_done",
+		"item_id":  "item_1",
+	}))
+
+	s := next()
+	require.Equal(t, "resp_1", s.ResponseID)
+	require.Equal(t, "item_1", s.ItemID)
+	data, err := io.ReadAll(s)
+	require.NoError(t, err)
+	require.Len(t, data, 320)
+
+	// an interruption ends the stream of the playing item
+	sendAudioDelta(t, srv, "item_2", make([]byte, 160))
+	s = next()
+	require.Equal(t, "item_2", s.ItemID)
+	require.NoError(t, srv.Send(map[string]any{
+		"type":     "input_audio_buffer.speech_started",
+		"event_id": "evt_2",
+		"item_id":  "item_user",
+	}))
+	_, err = io.ReadAll(s)
+	require.ErrorIs(t, err, ErrInterrupted)
+
+	// late audio of the interrupted item is dropped
+	sendAudioDelta(t, srv, "item_2", make([]byte, 160))
+	sendAudioDelta(t, srv, "item_3", make([]byte, 160))
+	s = next()
+	require.Equal(t, "item_3", s.ItemID)
+
+	// until its response is done
+	require.NoError(t, srv.Send(map[string]any{
+		"type":     "response.done",
+		"event_id": "evt_3",
+		"response": map[string]any{"id": "resp_1", "output": []map[string]any{{"id": "item_2", "type": "message"}}},
+	}))
+	require.Eventually(t, func() bool {
+		client.streams.mu.Lock()
+		defer client.streams.mu.Unlock()
+		return len(client.streams.dropped) == 0
+	}, time.Second, 5*time.Millisecond)
+
+	// closing the client ends the open streams and the channel
+	require.NoError(t, client.Close(ctx))
+	_, err = io.ReadAll(s)
+	require.ErrorIs(t, err, ErrClosed)
+	_, ok := <-client.OutputStreams()
+	require.False(t, ok)
+}
+
+func TestInputAudioClose(t *testing.T) {
+	client, _ := openTestClient(t)
+
+	in := client.InputAudio()
+	require.NoError(t, in.Close())
+
+	_, err := in.Write(make([]byte, 320))
+	require.ErrorIs(t, err, io.ErrClosedPipe)
+}
+
+func TestOutputStreamsExcludePlayout(t *testing.T) {
+	client := New(WithKey("test"), WithOutputStreams(), WithPlayout(PlayoutConfig{}))
+	require.Error(t, client.Open(context.Background()))
+}
@@ -0,0 +1,285 @@
+package openairt
+
+import (
+	"context"
+	"encoding/binary"
+	"github.com/codewandler/openairt-go/audio/g711"
+	"github.com/codewandler/openairt-go/events"
+	"io"
+	"sync"
+	"time"
+)
+
+// PlayoutConfig configures the playout buffer, see WithPlayout.
+type PlayoutConfig struct {
+	// Frame is the duration of the audio released at once, 20ms by default.
+	Frame time.Duration
+	// FadeOut is the duration of the fade applied to the playing audio when
+	// the user interrupts. Zero stops it at once.
+	FadeOut time.Duration
+}
+
+// Playhead is the position of the audio released by the playout buffer.
+type Playhead struct {
+	// ItemID is the conversation item currently playing, empty if none.
+	ItemID string
+	// Offset is the amount of audio of the item released so far.
+	Offset time.Duration
+	// Underruns counts the frames of silence inserted because the audio of
+	// the item did not arrive in time.
+	Underruns int
+}
+
+type playoutChunk struct {
+	itemID string
+	data   []byte
+}
+
+// playout releases response audio to the buffer read via Client.Audio at
+// real time, one frame at a time.
+type playout struct {
+	mu         sync.Mutex
+	config     PlayoutConfig
+	format     events.AudioFormat
+	sampleSize int
+	frameBytes int
+	rate       int
+	out        io.Writer
+	onRelease  func(itemID string, n int)
+
+	queue    []playoutChunk
+	done     map[string]bool // items whose audio is complete
+	dropped  map[string]bool // interrupted items, late audio is ignored
+	playhead Playhead
+	played   int // bytes of the playing item released
+
+	// fading is the number of bytes left to fade out, fadeBytes the length of
+	// the whole fade
+	fading    int
+	fadeBytes int
+}
+
+// newPlayout creates a playout writing to out. onRelease, if set, is called
+// before each frame is written.
+func newPlayout(config PlayoutConfig, format events.AudioFormat, rate, sampleSize int, out io.Writer, onRelease func(itemID string, n int)) *playout {
+	if config.Frame <= 0 {
+		config.Frame = 20 * time.Millisecond
+	}
+
+	frameBytes := int(float64(rate)*config.Frame.Seconds()) * sampleSize
+
+	return &playout{
+		config:     config,
+		format:     format,
+		sampleSize: sampleSize,
+		frameBytes: frameBytes,
+		rate:       rate,
+		out:        out,
+		onRelease:  onRelease,
+		done:       map[string]bool{},
+		dropped:    map[string]bool{},
+		fadeBytes:  int(float64(rate)*config.FadeOut.Seconds()) * sampleSize,
+	}
+}
+
+// write queues audio of an item.
+func (p *playout) write(itemID string, data []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.dropped[itemID] {
+		return
+	}
+	if p.fading > 0 {
+		// new audio after an interruption replaces the faded one
+		p.reset()
+	}
+
+	if n := len(p.queue); n > 0 && p.queue[n-1].itemID == itemID {
+		p.queue[n-1].data = append(p.queue[n-1].data, data...)
+		return
+	}
+	p.queue = append(p.queue, playoutChunk{itemID: itemID, data: append([]byte(nil), data...)})
+}
+
+// audioDone marks the audio of an item as complete, so running out of it is
+// no underrun.
+func (p *playout) audioDone(itemID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.dropped[itemID] {
+		// no more audio of the item will arrive
+		delete(p.dropped, itemID)
+		return
+	}
+	p.done[itemID] = true
+}
+
+// responseDone forgets the interrupted items of a finished response, a
+// cancelled response may end without their audio being done.
+func (p *playout) responseDone(itemIDs []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, id := range itemIDs {
+		delete(p.dropped, id)
+	}
+}
+
+// flush drops all queued audio.
+func (p *playout) flush() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.reset()
+}
+
+func (p *playout) reset() {
+	p.queue = nil
+	p.fading = 0
+	p.playhead = Playhead{Underruns: p.playhead.Underruns}
+	p.played = 0
+	clear(p.done)
+}
+
+// interrupt fades out the playing audio and drops the rest, including audio
+// of the interrupted items arriving later. It reports whether an item was
+// interrupted.
+func (p *playout) interrupt() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// a second interruption during the fade interrupts nothing new
+	interrupted := p.fading == 0 && (p.playhead.ItemID != "" || len(p.queue) > 0)
+	if p.playhead.ItemID != "" {
+		p.dropped[p.playhead.ItemID] = true
+	}
+	for _, c := range p.queue {
+		p.dropped[c.itemID] = true
+	}
+
+	if p.fadeBytes == 0 || len(p.queue) == 0 {
+		p.reset()
+		return interrupted
+	}
+	p.fading = p.fadeBytes
+	return interrupted
+}
+
+// position returns the playhead.
+func (p *playout) position() Playhead {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.playhead
+}
+
+// next returns the next frame to release and its item, a nil frame if there
+// is nothing to play.
+func (p *playout) next() ([]byte, string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.queue) == 0 {
+		if p.playhead.ItemID == "" || p.done[p.playhead.ItemID] {
+			p.playhead.ItemID = ""
+			return nil, ""
+		}
+
+		// the item is not complete yet, keep the clock running
+		p.playhead.Underruns++
+		p.advance(p.frameBytes)
+		return make([]byte, p.frameBytes), p.playhead.ItemID
+	}
+
+	chunk := &p.queue[0]
+	if chunk.itemID != p.playhead.ItemID {
+		delete(p.done, p.playhead.ItemID)
+		p.playhead.ItemID = chunk.itemID
+		p.played = 0
+	}
+
+	n := min(p.frameBytes, len(chunk.data))
+	if p.fading > 0 {
+		n = min(n, p.fading)
+	}
+	frame := chunk.data[:n:n]
+	chunk.data = chunk.data[n:]
+	if len(chunk.data) == 0 {
+		p.queue = p.queue[1:]
+	}
+
+	if p.fading > 0 {
+		from := float64(p.fading) / float64(p.fadeBytes)
+		p.fading -= n
+		to := float64(p.fading) / float64(p.fadeBytes)
+		frame = fade(frame, p.format, from, to)
+
+		if p.fading <= 0 {
+			p.reset()
+			return frame, chunk.itemID
+		}
+	}
+
+	p.advance(n)
+	return frame, chunk.itemID
+}
+
+func (p *playout) advance(n int) {
+	p.played += n
+	p.playhead.Offset = time.Duration(p.played/p.sampleSize) * time.Second / time.Duration(p.rate)
+}
+
+// run releases a frame per frame duration until ctx is done.
+func (p *playout) run(ctx context.Context) {
+	next := time.Now()
+	for {
+		next = next.Add(p.config.Frame)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(next)):
+		}
+
+		frame, itemID := p.next()
+		if frame == nil {
+			// idle, restart the clock with the next audio
+			next = time.Now()
+			continue
+		}
+		if p.onRelease != nil {
+			p.onRelease(itemID, len(frame))
+		}
+		_, _ = p.out.Write(frame)
+	}
+}
+
+// fade ramps the gain of a frame linearly from one value to another.
+func fade(frame []byte, format events.AudioFormat, from, to float64) []byte {
+	var pcm []byte
+	switch format {
+	case events.AudioFormatG711ULaw:
+		pcm = g711.DecodeULaw(frame)
+	case events.AudioFormatG711ALaw:
+		pcm = g711.DecodeALaw(frame)
+	default:
+		pcm = append([]byte(nil), frame...)
+	}
+
+	samples := len(pcm) / 2
+	for i := 0; i < samples; i++ {
+		gain := from + (to-from)*float64(i)/float64(samples)
+		v := float64(int16(binary.LittleEndian.Uint16(pcm[i*2:])))
+		binary.LittleEndian.PutUint16(pcm[i*2:], uint16(int16(v*gain)))
+	}
+
+	switch format {
+	case events.AudioFormatG711ULaw:
+		return g711.EncodeULaw(pcm)
+	case events.AudioFormatG711ALaw:
+		return g711.EncodeALaw(pcm)
+	default:
+		return pcm
+	}
+}
@@ -0,0 +1,137 @@
+package openairt
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"github.com/codewandler/openairt-go/events"
+	"github.com/stretchr/testify/require"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestPlayoutFrames(t *testing.T) {
+	p := newPlayout(PlayoutConfig{}, events.AudioFormatPCM16, 8_000, 2, io.Discard, nil)
+
+	// 50ms of audio in two deltas
+	p.write("item_1", constant(1, 200))
+	p.write("item_1", constant(1, 200))
+
+	for _, n := range []int{320, 320, 160} {
+		frame, itemID := p.next()
+		require.Len(t, frame, n)
+		require.Equal(t, "item_1", itemID)
+	}
+	require.Equal(t, Playhead{ItemID: "item_1", Offset: 50 * time.Millisecond}, p.position())
+
+	// the item is not complete, the clock keeps running with silence
+	frame, _ := p.next()
+	require.Equal(t, make([]byte, 320), frame)
+	require.Equal(t, Playhead{ItemID: "item_1", Offset: 70 * time.Millisecond, Underruns: 1}, p.position())
+
+	p.audioDone("item_1")
+	frame, _ = p.next()
+	require.Nil(t, frame)
+	require.Empty(t, p.position().ItemID)
+
+	// a new item starts at offset zero
+	p.write("item_2", constant(1, 160))
+	_, itemID := p.next()
+	require.Equal(t, "item_2", itemID)
+	require.Equal(t, 20*time.Millisecond, p.position().Offset)
+
+	p.write("item_2", constant(1, 160))
+	p.flush()
+	frame, _ = p.next()
+	require.Nil(t, frame)
+}
+
+func TestPlayoutFadeOut(t *testing.T) {
+	p := newPlayout(PlayoutConfig{FadeOut: 20 * time.Millisecond}, events.AudioFormatPCM16, 8_000, 2, io.Discard, nil)
+
+	p.write("item_1", constant(1000, 800))
+	frame, _ := p.next()
+	require.Equal(t, constant(1000, 160), frame)
+
+	p.interrupt()
+	frame, _ = p.next()
+	require.Len(t, frame, 320)
+	require.Equal(t, int16(1000), int16(binary.LittleEndian.Uint16(frame)))
+	require.Less(t, int16(binary.LittleEndian.Uint16(frame[318:])), int16(10))
+
+	frame, _ = p.next()
+	require.Nil(t, frame)
+
+	// late audio of the interrupted item is dropped
+	p.write("item_1", constant(1000, 160))
+	frame, _ = p.next()
+	require.Nil(t, frame)
+
+	// until the item or its response is done
+	p.audioDone("item_1")
+	require.Empty(t, p.dropped)
+	p.write("item_2", constant(1000, 160))
+	require.True(t, p.interrupt())
+	p.responseDone([]string{"item_2"})
+	require.Empty(t, p.dropped)
+	require.False(t, p.interrupt())
+}
+
+func TestPlayoutClient(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, srv := openTestClient(t, WithSampleRate(8_000), WithPlayout(PlayoutConfig{Frame: 10 * time.Millisecond}))
+
+	// 200ms of audio arrive at once
+	require.NoError(t, srv.Send(map[string]any{
+		"type":     "response.audio.delta",
+		"event_id": "evt_1",
+		"item_id":  "item_1",
+		"delta":    base64.StdEncoding.EncodeToString(constant(1, 4800)),
+	}))
+	require.NoError(t, srv.Send(map[string]any{
+		"type":     "response.audio.done",
+		"event_id": "evt_2",
+		"item_id":  "item_1",
+	}))
+
+	start := time.Now()
+	buf := make([]byte, 1400*2)
+	_, err := io.ReadFull(client.Audio(), buf)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, time.Since(start), 160*time.Millisecond)
+
+	require.Eventually(t, func() bool {
+		return client.Playhead().ItemID == ""
+	}, time.Second, time.Millisecond)
+	require.NoError(t, ctx.Err())
+}
+
+func TestPlayoutInterrupted(t *testing.T) {
+	client, srv := openTestClient(t, WithSampleRate(8_000), WithPlayout(PlayoutConfig{Frame: 10 * time.Millisecond}))
+	out := client.OutputAudio()
+
+	sendAudioDelta(t, srv, "item_1", constant(1, 4800))
+	buf := make([]byte, 160)
+	_, err := io.ReadFull(out, buf)
+	require.NoError(t, err)
+
+	require.NoError(t, srv.Send(map[string]any{
+		"type":     "input_audio_buffer.speech_started",
+		"event_id": "evt_2",
+		"item_id":  "item_user",
+	}))
+	require.Eventually(t, func() bool {
+		return client.interrupts.Load() == 1
+	}, time.Second, 5*time.Millisecond)
+
+	_, err = out.Read(buf)
+	require.ErrorIs(t, err, ErrInterrupted)
+
+	// the next response is read as usual
+	sendAudioDelta(t, srv, "item_2", constant(1, 160))
+	_, err = io.ReadFull(out, buf)
+	require.NoError(t, err)
+}
@@ -0,0 +1,95 @@
+package openairt
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"github.com/codewandler/openairt-go/openairttest"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestProxyAndNetDial(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	srv := openairttest.NewServer()
+	defer srv.Close()
+
+	var connects atomic.Int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			http.Error(w, "CONNECT only", http.StatusMethodNotAllowed)
+			return
+		}
+		target, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer target.Close()
+		connects.Add(1)
+
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		go func() {
+			_, _ = io.Copy(target, conn)
+		}()
+		_, _ = io.Copy(conn, target)
+	}))
+	defer proxy.Close()
+	proxyURL, err := url.Parse(proxy.URL)
+	require.NoError(t, err)
+
+	var dialed atomic.Value
+	client := New(
+		WithKey("test"),
+		WithBaseURL(srv.URL),
+		WithProxy(http.ProxyURL(proxyURL)),
+		WithNetDial(func(ctx context.Context, network, addr string) (net.Conn, error) {
+			dialed.Store(addr)
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		}),
+	)
+	require.NoError(t, client.Open(ctx))
+
+	_, err = srv.Next(ctx, "session.update")
+	require.NoError(t, err)
+	require.EqualValues(t, 1, connects.Load())
+	// the dialer connects to the proxy, which connects to the server
+	require.Equal(t, proxyURL.Host, dialed.Load())
+}
+
+func TestTLSOptions(t *testing.T) {
+	base := &tls.Config{ServerName: "example.com"}
+	pool := x509.NewCertPool()
+	cert := tls.Certificate{Certificate: [][]byte{{1}}}
+
+	config := &clientConfig{}
+	WithOptions(WithTLSConfig(base), WithRootCAs(pool), WithClientCertificate(cert))(config)
+
+	require.Equal(t, "example.com", config.tlsConfig.ServerName)
+	require.Same(t, pool, config.tlsConfig.RootCAs)
+	require.Len(t, config.tlsConfig.Certificates, 1)
+	// the config passed in is left alone
+	require.Nil(t, base.RootCAs)
+	require.Empty(t, base.Certificates)
+	// and not used for the proxy
+	require.Nil(t, config.proxyTLSConfig)
+
+	proxyConfig := &tls.Config{RootCAs: x509.NewCertPool()}
+	WithProxyTLSConfig(proxyConfig)(config)
+	require.Same(t, proxyConfig, config.proxyTLSConfig)
+	require.Same(t, pool, config.tlsConfig.RootCAs)
+}
@@ -0,0 +1,196 @@
+package openairt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/codewandler/openairt-go/events"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited matches a RateLimitError with errors.Is.
+var ErrRateLimited = errors.New("rate limited")
+
+// RateLimitError is returned by a fail fast RateLimiter when a limit is
+// exhausted.
+type RateLimitError struct {
+	// Name is the exhausted limit, requests or tokens.
+	Name string
+	// RetryAfter is the time until the limit resets.
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limit %s exhausted, retry after %s", e.Name, e.RetryAfter)
+}
+
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// RateLimitStatus is the known state of a rate limit.
+type RateLimitStatus struct {
+	Name      string
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// RateLimiterConfig configures a RateLimiter.
+type RateLimiterConfig struct {
+	// FailFast makes Wait return a RateLimitError instead of waiting for
+	// the reset of an exhausted limit.
+	FailFast bool
+	// MinTokens is the number of tokens a response is expected to need. A
+	// tokens limit with fewer remaining counts as exhausted.
+	MinTokens int
+}
+
+// RateLimiter holds back responses before the server would reject them. It
+// learns the limits from rate_limits.updated and is meant to be shared by all
+// clients using the same key, see WithRateLimiter.
+type RateLimiter struct {
+	config RateLimiterConfig
+
+	mu     sync.Mutex
+	limits map[string]*RateLimitStatus
+}
+
+// NewRateLimiter creates a RateLimiter without known limits.
+func NewRateLimiter(config RateLimiterConfig) *RateLimiter {
+	return &RateLimiter{
+		config: config,
+		limits: map[string]*RateLimitStatus{},
+	}
+}
+
+// Update records the limits reported by the server.
+func (l *RateLimiter) Update(limits []events.RateLimit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	for _, rl := range limits {
+		l.limits[rl.Name] = &RateLimitStatus{
+			Name:      rl.Name,
+			Limit:     rl.Limit,
+			Remaining: rl.Remaining,
+			Reset:     now.Add(time.Duration(rl.ResetSeconds * float64(time.Second))),
+		}
+	}
+}
+
+// Limits returns the known limits ordered by name.
+func (l *RateLimiter) Limits() []RateLimitStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refresh(time.Now())
+
+	out := make([]RateLimitStatus, 0, len(l.limits))
+	for _, s := range l.limits {
+		out = append(out, *s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Wait blocks until no known limit is exhausted and counts a request. A fail
+// fast limiter returns a RateLimitError instead of blocking.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		name, wait := l.exhausted(time.Now())
+		if wait <= 0 {
+			// count the request until the server reports the limits again,
+			// so concurrent clients don't all pass
+			if s, ok := l.limits["requests"]; ok {
+				s.Remaining--
+			}
+			l.mu.Unlock()
+			return nil
+		}
+		l.mu.Unlock()
+
+		if l.config.FailFast {
+			return &RateLimitError{Name: name, RetryAfter: wait}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// refresh restores the limits whose reset has passed.
+func (l *RateLimiter) refresh(now time.Time) {
+	for _, s := range l.limits {
+		if !s.Reset.IsZero() && !now.Before(s.Reset) {
+			s.Remaining = s.Limit
+			s.Reset = time.Time{}
+		}
+	}
+}
+
+// exhausted returns the exhausted limit that resets last and the time until
+// then.
+func (l *RateLimiter) exhausted(now time.Time) (string, time.Duration) {
+	l.refresh(now)
+
+	var (
+		name string
+		wait time.Duration
+	)
+	for _, s := range l.limits {
+		reserve := 0
+		if s.Name == "tokens" {
+			reserve = l.config.MinTokens
+		}
+		if s.Remaining > reserve || s.Reset.IsZero() {
+			continue
+		}
+		if d := s.Reset.Sub(now); d > wait {
+			name, wait = s.Name, d
+		}
+	}
+	return name, wait
+}
+
+// RateLimits returns the limits last reported to this client.
+func (c *Client) RateLimits() []events.RateLimit {
+	c.rateLimitsMu.Lock()
+	defer c.rateLimitsMu.Unlock()
+
+	return append([]events.RateLimit(nil), c.rateLimits...)
+}
+
+func (c *Client) handleRateLimits(evt *events.RateLimitsUpdatedEvent) {
+	c.rateLimitsMu.Lock()
+	c.rateLimits = evt.RateLimits
+	c.rateLimitsMu.Unlock()
+
+	if c.config.limiter != nil {
+		c.config.limiter.Update(evt.RateLimits)
+	}
+}
+
+// beforeResponse checks the budget and waits for the rate limiter before a
+// response is requested.
+func (c *Client) beforeResponse() error {
+	if c.usage.overBudget() {
+		return ErrBudgetExceeded
+	}
+	if c.config.limiter == nil {
+		return nil
+	}
+
+	ctx := c.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return c.config.limiter.Wait(ctx)
+}
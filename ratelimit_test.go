@@ -0,0 +1,92 @@
+package openairt
+
+import (
+	"context"
+	"errors"
+	"github.com/codewandler/openairt-go/events"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterFailFast(t *testing.T) {
+	l := NewRateLimiter(RateLimiterConfig{FailFast: true, MinTokens: 100})
+
+	// unknown limits don't hold back
+	require.NoError(t, l.Wait(context.Background()))
+
+	l.Update([]events.RateLimit{
+		{Name: "requests", Limit: 10, Remaining: 1, ResetSeconds: 60},
+		{Name: "tokens", Limit: 1000, Remaining: 500, ResetSeconds: 30},
+	})
+	require.NoError(t, l.Wait(context.Background()))
+
+	// the request was counted
+	err := l.Wait(context.Background())
+	require.ErrorIs(t, err, ErrRateLimited)
+	var rlErr *RateLimitError
+	require.True(t, errors.As(err, &rlErr))
+	require.Equal(t, "requests", rlErr.Name)
+	require.InDelta(t, 60*time.Second, rlErr.RetryAfter, float64(time.Second))
+
+	// too few tokens left for a response
+	l.Update([]events.RateLimit{
+		{Name: "requests", Limit: 10, Remaining: 5, ResetSeconds: 60},
+		{Name: "tokens", Limit: 1000, Remaining: 50, ResetSeconds: 30},
+	})
+	require.True(t, errors.As(l.Wait(context.Background()), &rlErr))
+	require.Equal(t, "tokens", rlErr.Name)
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	l := NewRateLimiter(RateLimiterConfig{})
+	l.Update([]events.RateLimit{{Name: "requests", Limit: 10, Remaining: 0, ResetSeconds: 0.1}})
+
+	start := time.Now()
+	require.NoError(t, l.Wait(context.Background()))
+	require.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+
+	// the limit is restored after the reset
+	limits := l.Limits()
+	require.Len(t, limits, 1)
+	require.Equal(t, 9, limits[0].Remaining)
+
+	l.Update([]events.RateLimit{{Name: "requests", Limit: 10, Remaining: 0, ResetSeconds: 60}})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, l.Wait(ctx), context.DeadlineExceeded)
+}
+
+func TestClientRateLimits(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	limiter := NewRateLimiter(RateLimiterConfig{FailFast: true})
+	client, srv := openTestClient(t, WithRateLimiter(limiter))
+	other, _ := openTestClient(t, WithRateLimiter(limiter))
+
+	require.NoError(t, srv.Send(map[string]any{
+		"type":     "rate_limits.updated",
+		"event_id": "evt_1",
+		"rate_limits": []map[string]any{
+			{"name": "requests", "limit": 1000, "remaining": 0, "reset_seconds": 30},
+			{"name": "tokens", "limit": 50000, "remaining": 49000, "reset_seconds": 0.5},
+		},
+	}))
+	require.Eventually(t, func() bool {
+		return len(client.RateLimits()) == 2
+	}, time.Second, 5*time.Millisecond)
+	require.Equal(t, 49000, client.RateLimits()[1].Remaining)
+
+	// the limiter is shared with clients that did not see the event
+	require.ErrorIs(t, other.CreateResponse(), ErrRateLimited)
+
+	// no item is added for a refused response
+	require.ErrorIs(t, client.UserInput("hello", true), ErrRateLimited)
+	require.NoError(t, client.UserInput("hello", false))
+	evt, err := srv.NextOf(ctx, "conversation.item.create", "response.create")
+	require.NoError(t, err)
+	var item events.ConversationItemCreateEvent
+	require.NoError(t, evt.Decode(&item))
+	require.Equal(t, "hello", item.Item.Content[0].Text)
+}
@@ -0,0 +1,245 @@
+package openairt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/codewandler/openairt-go/audio/g711"
+	"github.com/codewandler/openairt-go/audio/wav"
+	"github.com/codewandler/openairt-go/events"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	recorderUser = iota
+	recorderAgent
+)
+
+// Recorder records a call as stereo WAV, the user on the left and the agent
+// on the right channel. It taps the audio written to and read from
+// Client.Audio, see WithRecorder.
+//
+// Both directions are placed on a common wall clock timeline: audio starts at
+// the time it is written or read, or right after the previous audio of the
+// same direction, gaps are filled with silence. Agent audio read ahead of
+// time is cut off when the user barges in.
+type Recorder struct {
+	mu      sync.Mutex
+	w       *wav.Writer
+	owner   *clientConfig
+	out     io.WriteSeeker
+	markers bool
+	decode  [2]func([]byte) []byte
+	size    [2]int // bytes per sample of the client audio
+	rate    int
+	start   time.Time
+	now     func() time.Time
+	closed  bool
+	err     error
+
+	// flushed is the number of frames written to w, pending holds the samples
+	// of each channel from there on
+	flushed int64
+	pending [2][]int16
+
+	// agent items by their offset in the audio read from the client
+	items    []recorderItem
+	received int64
+	played   int64
+}
+
+type recorderItem struct {
+	id     string
+	offset int64
+}
+
+// NewRecorder creates a Recorder writing to w. If markers is set, the start
+// of each conversation item is stored as a cue point labeled with its role
+// and ID.
+func NewRecorder(w io.WriteSeeker, markers bool) *Recorder {
+	return &Recorder{
+		out:     w,
+		markers: markers,
+		now:     time.Now,
+	}
+}
+
+// begin writes the WAV header once the client knows its audio format. A
+// client opened again keeps recording on the same timeline.
+func (r *Recorder) begin(config *clientConfig) error {
+	rate, inSize := config.audioRate(config.inFormat)
+	outRate, outSize := config.audioRate(config.outFormat)
+	if rate != outRate {
+		return fmt.Errorf("recorder needs the same input and output sample rate, got %d and %d", rate, outRate)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.w != nil {
+		if r.owner == config {
+			return nil
+		}
+		return errors.New("recorder already in use")
+	}
+
+	w, err := wav.NewWriter(r.out, rate, 2)
+	if err != nil {
+		return fmt.Errorf("start recording: %w", err)
+	}
+
+	r.w = w
+	r.owner = config
+	r.rate = rate
+	r.decode = [2]func([]byte) []byte{recorderDecoder(config.inFormat), recorderDecoder(config.outFormat)}
+	r.size = [2]int{inSize, outSize}
+	r.start = r.now()
+
+	return nil
+}
+
+// recorderDecoder returns the conversion of the audio to PCM16.
+func recorderDecoder(f events.AudioFormat) func([]byte) []byte {
+	switch f {
+	case events.AudioFormatG711ULaw:
+		return g711.DecodeULaw
+	case events.AudioFormatG711ALaw:
+		return g711.DecodeALaw
+	default:
+		return nil
+	}
+}
+
+// clock returns the current position on the timeline in frames.
+func (r *Recorder) clock() int64 {
+	return int64(r.now().Sub(r.start).Seconds() * float64(r.rate))
+}
+
+// add places audio of a channel on the timeline.
+func (r *Recorder) add(channel int, data []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.w == nil || r.closed {
+		return
+	}
+
+	now := r.clock()
+	start := max(now, r.flushed+int64(len(r.pending[channel])))
+
+	if channel == recorderAgent {
+		r.markItem(start, int64(len(data)))
+	}
+
+	if decode := r.decode[channel]; decode != nil {
+		data = decode(data)
+	}
+
+	buf := r.pending[channel]
+	for int64(len(buf)) < start-r.flushed {
+		buf = append(buf, 0)
+	}
+	for i := 0; i+1 < len(data); i += 2 {
+		buf = append(buf, int16(binary.LittleEndian.Uint16(data[i:])))
+	}
+	r.pending[channel] = buf
+
+	r.flush(now)
+}
+
+// markItem adds cues for the agent items starting in the next n bytes read
+// from the client, placed at frame start.
+func (r *Recorder) markItem(start, n int64) {
+	for len(r.items) > 0 && r.items[0].offset < r.played+n {
+		if r.markers {
+			offset := (r.items[0].offset - r.played) / int64(r.size[recorderAgent])
+			r.w.AddCue(start+max(offset, 0), "agent "+r.items[0].id)
+		}
+		r.items = r.items[1:]
+	}
+	r.played += n
+}
+
+// agentItem notes that the audio of an agent item follows n bytes of audio
+// passed to the client.
+func (r *Recorder) agentItem(id string, n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.items) == 0 || r.items[len(r.items)-1].id != id {
+		r.items = append(r.items, recorderItem{id: id, offset: r.received})
+	}
+	r.received += int64(n)
+}
+
+// interrupt drops agent audio placed after now, the output buffer of the
+// client was reset by the user speaking.
+func (r *Recorder) interrupt(userItemID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.w == nil || r.closed {
+		return
+	}
+
+	now := r.clock()
+	if keep := now - r.flushed; keep >= 0 && int64(len(r.pending[recorderAgent])) > keep {
+		r.pending[recorderAgent] = r.pending[recorderAgent][:keep]
+	}
+
+	r.items = nil
+	r.played = r.received
+
+	if r.markers && userItemID != "" {
+		r.w.AddCue(now, "user "+userItemID)
+	}
+}
+
+// flush writes the frames up to frame to the file.
+func (r *Recorder) flush(frame int64) {
+	n := int(frame - r.flushed)
+	if n <= 0 {
+		return
+	}
+
+	buf := make([]byte, n*4)
+	for c, samples := range r.pending {
+		for i, s := range samples[:min(n, len(samples))] {
+			binary.LittleEndian.PutUint16(buf[i*4+c*2:], uint16(s))
+		}
+		if len(samples) > n {
+			r.pending[c] = samples[n:]
+		} else {
+			r.pending[c] = samples[:0]
+		}
+	}
+
+	// errors of the writer surface on Close
+	if _, err := r.w.Write(buf); err != nil && r.err == nil {
+		r.err = err
+	}
+	r.flushed = frame
+}
+
+// Close writes the remaining audio of both channels and finalizes the file.
+// It does not close the underlying writer.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.w == nil {
+		return errors.New("recorder not started")
+	}
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+
+	r.flush(r.flushed + int64(max(len(r.pending[recorderUser]), len(r.pending[recorderAgent]))))
+	if err := r.w.Close(); err != nil {
+		return err
+	}
+	return r.err
+}
@@ -0,0 +1,173 @@
+package openairt
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"github.com/codewandler/openairt-go/audio/wav"
+	"github.com/codewandler/openairt-go/openairttest"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func constant(value int16, n int) []byte {
+	b := make([]byte, n*2)
+	for i := 0; i < n; i++ {
+		binary.LittleEndian.PutUint16(b[i*2:], uint16(value))
+	}
+	return b
+}
+
+// readRecording returns the left and right channel of a recording.
+func readRecording(t *testing.T, data []byte) (user, agent []int16) {
+	t.Helper()
+
+	r, err := wav.NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, 2, r.Channels)
+
+	pcm, err := io.ReadAll(r)
+	require.NoError(t, err)
+	for i := 0; i+3 < len(pcm); i += 4 {
+		user = append(user, int16(binary.LittleEndian.Uint16(pcm[i:])))
+		agent = append(agent, int16(binary.LittleEndian.Uint16(pcm[i+2:])))
+	}
+	return user, agent
+}
+
+func TestRecorderTimeline(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "call.wav"))
+	require.NoError(t, err)
+	defer f.Close()
+
+	now := time.Unix(0, 0)
+	rec := NewRecorder(f, true)
+	rec.now = func() time.Time { return now }
+	require.NoError(t, rec.begin(New(WithSampleRate(8_000)).config))
+
+	// 10ms of user audio and 100ms of agent audio read ahead at once
+	rec.add(recorderUser, constant(1, 80))
+	rec.agentItem("item_a", 1600)
+	rec.add(recorderAgent, constant(2, 800))
+
+	// the user barges in after 50ms
+	now = now.Add(50 * time.Millisecond)
+	rec.interrupt("item_u")
+
+	// the user keeps speaking after a gap
+	now = now.Add(150 * time.Millisecond)
+	rec.add(recorderUser, constant(3, 80))
+	require.NoError(t, rec.Close())
+
+	data, err := os.ReadFile(f.Name())
+	require.NoError(t, err)
+	user, agent := readRecording(t, data)
+	require.Len(t, user, 1680)
+
+	require.Equal(t, []int16{1, 1, 0}, user[78:81])
+	require.Equal(t, []int16{0, 3}, user[1599:1601])
+	require.Equal(t, int16(3), user[1679])
+
+	require.Equal(t, int16(2), agent[0])
+	require.Equal(t, []int16{2, 0}, agent[399:401])
+	require.Equal(t, int16(0), agent[1679])
+
+	cues := data[bytes.Index(data, []byte("cue ")):]
+	require.Equal(t, uint32(2), binary.LittleEndian.Uint32(cues[8:]))
+	require.Equal(t, uint32(0), binary.LittleEndian.Uint32(cues[16:]))
+	require.Equal(t, uint32(400), binary.LittleEndian.Uint32(cues[16+24:]))
+	require.Contains(t, string(cues), "agent item_a\x00")
+	require.Contains(t, string(cues), "user item_u\x00")
+}
+
+func TestRecorderClient(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "call.wav"))
+	require.NoError(t, err)
+	defer f.Close()
+
+	rec := NewRecorder(f, true)
+	client, srv := openTestClient(t, WithRecorder(rec))
+
+	_, err = client.Audio().Write(constant(1, 480))
+	require.NoError(t, err)
+	_, err = srv.Next(ctx, "input_audio_buffer.append")
+	require.NoError(t, err)
+
+	require.NoError(t, srv.Send(map[string]any{
+		"type":     "response.audio.delta",
+		"event_id": "evt_1",
+		"item_id":  "item_agent",
+		"delta":    base64.StdEncoding.EncodeToString(constant(2, 480)),
+	}))
+
+	buf := make([]byte, 960)
+	_, err = io.ReadFull(client.Audio(), buf)
+	require.NoError(t, err)
+
+	require.NoError(t, srv.Send(map[string]any{
+		"type":     "input_audio_buffer.speech_started",
+		"event_id": "evt_2",
+		"item_id":  "item_user",
+	}))
+	require.Eventually(t, func() bool {
+		rec.mu.Lock()
+		defer rec.mu.Unlock()
+		return len(rec.w.Cues()) == 2
+	}, time.Second, time.Millisecond)
+
+	require.NoError(t, rec.Close())
+
+	data, err := os.ReadFile(f.Name())
+	require.NoError(t, err)
+	user, agent := readRecording(t, data)
+	require.Contains(t, user, int16(1))
+	require.Contains(t, agent, int16(2))
+	require.Contains(t, string(data), "agent item_agent\x00")
+	require.Contains(t, string(data), "user item_user\x00")
+}
+
+func TestRecorderReopen(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	srv := openairttest.NewServer()
+	defer srv.Close()
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "call.wav"))
+	require.NoError(t, err)
+	defer f.Close()
+
+	rec := NewRecorder(f, false)
+	var dials atomic.Int32
+	client := New(
+		WithKey("test"),
+		WithBaseURL(srv.URL),
+		WithRecorder(rec),
+		WithNetDial(func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if dials.Add(1) == 1 {
+				return nil, errors.New("network is down")
+			}
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		}),
+	)
+	require.ErrorContains(t, client.Open(ctx), "network is down")
+	require.NoError(t, client.Open(ctx))
+	require.NoError(t, client.Close(ctx))
+	require.NoError(t, client.Open(ctx))
+	defer client.Close(ctx)
+
+	// another client must not write into the same recording
+	other := New(WithKey("test"), WithBaseURL(srv.URL), WithRecorder(rec))
+	require.ErrorContains(t, other.Open(ctx), "recorder already in use")
+}
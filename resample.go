@@ -3,7 +3,9 @@ package openairt
 import (
 	"bytes"
 	"encoding/binary"
-	"github.com/faiface/beep"
+	"fmt"
+	"io"
+	"math"
 )
 
 type PCMStreamer struct {
@@ -34,29 +36,253 @@ func (s *PCMStreamer) Stream(samples [][2]float64) (n int, ok bool) {
 
 func (s *PCMStreamer) Err() error { return nil }
 
+// ResamplePCM converts a complete mono PCM16 buffer from one rate to another.
+// Use a Resampler for audio that arrives in chunks.
 func ResamplePCM(pcmData []byte, fromRate, toRate int) ([]byte, error) {
-	streamer := NewPCMStreamer(pcmData)
-
-	resampler := beep.Resample(4, beep.SampleRate(fromRate), beep.SampleRate(toRate), streamer)
-
-	// Buffer to collect the output
-	buf := new(bytes.Buffer)
-	sample := make([][2]float64, 1024)
-
-	for {
-		n, ok := resampler.Stream(sample)
-		for i := 0; i < n; i++ {
-			mono := (sample[i][0] + sample[i][1]) / 2.0
-			int16Val := int16(mono * 32767)
-			err := binary.Write(buf, binary.LittleEndian, int16Val)
-			if err != nil {
-				return nil, err
-			}
+	r, err := NewResampler(fromRate, toRate)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := r.Write(pcmData); err != nil {
+		return nil, err
+	}
+	r.Flush()
+
+	return r.out.Bytes(), nil
+}
+
+const (
+	// resamplerZeroCrossings is the number of sinc zero crossings on each side
+	// of the kernel center.
+	resamplerZeroCrossings = 16
+	// resamplerRolloff places the cutoff slightly below the target Nyquist
+	// frequency, so the transition band does not alias.
+	resamplerRolloff = 0.9
+)
+
+// Resampler converts a stream of mono PCM16 (little endian) audio between two
+// sample rates with a windowed sinc filter. Unlike ResamplePCM it keeps the
+// filter history between writes, so chunk boundaries are seamless.
+//
+// Write input audio and Read the converted audio. Read returns 0 bytes while
+// the converter waits for more input. Call Flush at the end of a stream to get
+// the remaining samples, Read returns io.EOF once they are read. The Resampler
+// can be reused afterward.
+//
+// Read never blocks, so the Resampler is not a general purpose io.Reader:
+// io.Copy from it spins until Flush. Read Buffered bytes after each Write
+// instead.
+type Resampler struct {
+	from, to int
+	up, down int64
+	taps     int
+	kernel   [][]float64
+
+	in      []float64 // input samples, in[0] has the index base
+	base    int64
+	next    int64 // index of the next output sample
+	written int64 // number of input samples of the current stream
+	odd     []byte
+	out     bytes.Buffer
+	flushed bool // the stream ended, Read returns io.EOF at the end of out
+}
+
+// NewResampler creates a Resampler from one sample rate to another.
+func NewResampler(from, to int) (*Resampler, error) {
+	if from <= 0 || to <= 0 {
+		return nil, fmt.Errorf("invalid sample rates: %d -> %d", from, to)
+	}
+
+	g := gcd(from, to)
+	r := &Resampler{
+		from: from,
+		to:   to,
+		up:   int64(to / g),
+		down: int64(from / g),
+	}
+
+	if from != to {
+		r.buildKernel()
+	}
+	r.Reset()
+
+	return r, nil
+}
+
+// buildKernel precomputes the filter weights for each of the up phases an
+// output sample can have between two input samples.
+func (r *Resampler) buildKernel() {
+	ratio := math.Min(1, float64(r.to)/float64(r.from))
+	cutoff := 0.5 * ratio * resamplerRolloff // cycles per input sample
+	r.taps = int(math.Ceil(resamplerZeroCrossings / ratio))
+
+	r.kernel = make([][]float64, r.up)
+	for p := range r.kernel {
+		frac := float64(p) / float64(r.up)
+		weights := make([]float64, 2*r.taps)
+
+		var sum float64
+		for j := range weights {
+			t := float64(j-r.taps+1) - frac
+			w := 2 * cutoff * sinc(2*cutoff*t) * blackman(t, float64(r.taps))
+			weights[j] = w
+			sum += w
+		}
+
+		// unity gain at DC
+		for j := range weights {
+			weights[j] /= sum
 		}
-		if !ok {
+		r.kernel[p] = weights
+	}
+}
+
+// Reset drops all buffered audio and starts a new stream.
+func (r *Resampler) Reset() {
+	r.restart()
+	r.out.Reset()
+	r.flushed = false
+}
+
+func (r *Resampler) restart() {
+	r.in = make([]float64, r.taps, r.taps+4096)
+	r.base = -int64(r.taps)
+	r.next = 0
+	r.written = 0
+	r.odd = r.odd[:0]
+}
+
+// Buffered returns the number of converted bytes ready to be read.
+func (r *Resampler) Buffered() int {
+	return r.out.Len()
+}
+
+// drain returns all converted audio. The slice is only valid until the next
+// call to Write.
+func (r *Resampler) drain() []byte {
+	return r.out.Next(r.out.Len())
+}
+
+// Write adds input audio.
+func (r *Resampler) Write(p []byte) (int, error) {
+	n := len(p)
+	r.flushed = false
+
+	if len(r.odd) > 0 {
+		r.odd = append(r.odd, p...)
+		p, r.odd = r.odd, nil
+	}
+	if len(p)%2 == 1 {
+		r.odd = append(r.odd, p[len(p)-1])
+		p = p[:len(p)-1]
+	}
+
+	if r.from == r.to {
+		r.out.Write(p)
+		return n, nil
+	}
+
+	for i := 0; i+1 < len(p); i += 2 {
+		r.in = append(r.in, float64(int16(binary.LittleEndian.Uint16(p[i:])))/32768)
+	}
+	r.written += int64(len(p) / 2)
+
+	r.process(r.written)
+
+	return n, nil
+}
+
+// Read reads converted audio.
+func (r *Resampler) Read(p []byte) (int, error) {
+	if r.out.Len() == 0 && !r.flushed {
+		return 0, nil
+	}
+	return r.out.Read(p)
+}
+
+// Flush converts the remaining input of the current stream, so it can be
+// read, and starts a new stream with the next Write.
+func (r *Resampler) Flush() {
+	if r.from != r.to && r.written > 0 {
+		// pad with silence so the last output samples see a full kernel
+		r.in = append(r.in, make([]float64, r.taps)...)
+		r.process(r.written + int64(r.taps))
+	}
+
+	r.restart()
+	r.flushed = true
+}
+
+// process computes all output samples whose kernel is covered by the first
+// available input samples.
+func (r *Resampler) process(available int64) {
+	total := (r.written*r.up + r.down - 1) / r.down
+	var buf [2]byte
+
+	for r.next < total {
+		pos := r.next * r.down
+		center := pos / r.up
+		if center+int64(r.taps) >= available {
 			break
 		}
+
+		start := int(center - int64(r.taps) + 1 - r.base)
+		weights := r.kernel[pos%r.up]
+		window := r.in[start : start+len(weights)]
+
+		var v float64
+		for j, w := range weights {
+			v += window[j] * w
+		}
+
+		binary.LittleEndian.PutUint16(buf[:], uint16(toInt16(v)))
+		r.out.Write(buf[:])
+		r.next++
 	}
 
-	return buf.Bytes(), nil
+	// keep the history the next output sample needs
+	first := (r.next*r.down)/r.up - int64(r.taps) + 1
+	if drop := int(first - r.base); drop > 0 && drop <= len(r.in) {
+		n := copy(r.in, r.in[drop:])
+		r.in = r.in[:n]
+		r.base = first
+	}
+}
+
+var _ io.ReadWriter = (*Resampler)(nil)
+
+func toInt16(v float64) int16 {
+	v = math.Round(v * 32768)
+	switch {
+	case v > math.MaxInt16:
+		return math.MaxInt16
+	case v < math.MinInt16:
+		return math.MinInt16
+	default:
+		return int16(v)
+	}
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// blackman is a Blackman window over [-half, half].
+func blackman(t, half float64) float64 {
+	if math.Abs(t) >= half {
+		return 0
+	}
+	x := math.Pi * t / half
+	return 0.42 + 0.5*math.Cos(x) + 0.08*math.Cos(2*x)
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
 }
@@ -0,0 +1,150 @@
+package openairt
+
+import (
+	"encoding/binary"
+	"github.com/stretchr/testify/require"
+	"io"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func sine(freq float64, rate, n int, amplitude float64) []byte {
+	b := make([]byte, n*2)
+	for i := 0; i < n; i++ {
+		v := amplitude * math.Sin(2*math.Pi*freq*float64(i)/float64(rate))
+		binary.LittleEndian.PutUint16(b[i*2:], uint16(int16(math.Round(v*32767))))
+	}
+	return b
+}
+
+func samples(b []byte) []float64 {
+	out := make([]float64, len(b)/2)
+	for i := range out {
+		out[i] = float64(int16(binary.LittleEndian.Uint16(b[i*2:]))) / 32767
+	}
+	return out
+}
+
+// resampleChunked feeds pcm in random chunk sizes, including odd ones.
+func resampleChunked(t *testing.T, pcm []byte, from, to int) []byte {
+	t.Helper()
+
+	r, err := NewResampler(from, to)
+	require.NoError(t, err)
+
+	rnd := rand.New(rand.NewSource(1))
+	var out []byte
+	for len(pcm) > 0 {
+		n := min(len(pcm), 1+rnd.Intn(700))
+		_, err := r.Write(pcm[:n])
+		require.NoError(t, err)
+		pcm = pcm[n:]
+
+		data, err := io.ReadAll(io.LimitReader(r, int64(r.Buffered())))
+		require.NoError(t, err)
+		out = append(out, data...)
+	}
+	r.Flush()
+
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return append(out, data...)
+}
+
+// snr compares got against a sine of the given frequency, skipping the edges.
+func snr(got []float64, freq float64, rate int, amplitude float64) float64 {
+	var signal, noise float64
+	for i := len(got) / 10; i < len(got)*9/10; i++ {
+		want := amplitude * math.Sin(2*math.Pi*freq*float64(i)/float64(rate))
+		signal += want * want
+		noise += (got[i] - want) * (got[i] - want)
+	}
+	return 10 * math.Log10(signal/noise)
+}
+
+// power returns the mean power of x at freq (goertzel).
+func power(x []float64, freq float64, rate int) float64 {
+	k := 2 * math.Cos(2*math.Pi*freq/float64(rate))
+	var s1, s2 float64
+	for _, v := range x {
+		s1, s2 = v+k*s1-s2, s1
+	}
+	return (s1*s1 + s2*s2 - k*s1*s2) / float64(len(x)*len(x))
+}
+
+func TestResampler(t *testing.T) {
+	rates := []int{8_000, 16_000, 24_000, 48_000}
+
+	for _, from := range rates {
+		for _, to := range rates {
+			pcm := sine(1000, from, from/2, 0.5)
+			out := resampleChunked(t, pcm, from, to)
+
+			// no samples lost or added
+			require.Len(t, out, (from/2*to+from-1)/from*2, "%d -> %d", from, to)
+
+			require.Greater(t, snr(samples(out), 1000, to, 0.5), 40.0, "%d -> %d", from, to)
+		}
+	}
+}
+
+func TestResamplerChunkBoundaries(t *testing.T) {
+	pcm := sine(440, 24_000, 24_000, 0.8)
+
+	oneShot, err := ResamplePCM(pcm, 24_000, 8_000)
+	require.NoError(t, err)
+
+	require.Equal(t, oneShot, resampleChunked(t, pcm, 24_000, 8_000))
+}
+
+func TestResamplerAntiAliasing(t *testing.T) {
+	// 6 kHz is above the Nyquist frequency of 8 kHz audio and would alias to
+	// 2 kHz without filtering
+	pcm := sine(6000, 48_000, 48_000, 0.8)
+	out := samples(resampleChunked(t, pcm, 48_000, 8_000))
+
+	in := power(samples(pcm), 6000, 48_000)
+	aliased := power(out, 2000, 8_000)
+	require.Less(t, 10*math.Log10(aliased/in), -60.0)
+}
+
+func TestResamplerReset(t *testing.T) {
+	r, err := NewResampler(24_000, 16_000)
+	require.NoError(t, err)
+
+	_, err = r.Write(sine(1000, 24_000, 2400, 0.5))
+	require.NoError(t, err)
+	require.NotZero(t, r.Buffered())
+
+	r.Reset()
+	require.Zero(t, r.Buffered())
+
+	_, err = NewResampler(0, 8_000)
+	require.Error(t, err)
+}
+
+func TestResamplerRead(t *testing.T) {
+	r, err := NewResampler(24_000, 16_000)
+	require.NoError(t, err)
+
+	// waiting for input is not the end of the stream
+	buf := make([]byte, 64)
+	n, err := r.Read(buf)
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	_, err = r.Write(sine(1000, 24_000, 2400, 0.5))
+	require.NoError(t, err)
+	out, err := io.ReadAll(io.LimitReader(r, int64(r.Buffered())))
+	require.NoError(t, err)
+	require.NotEmpty(t, out)
+	n, err = r.Read(buf)
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	r.Flush()
+	rest, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Len(t, append(out, rest...), 2*1600)
+}
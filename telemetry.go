@@ -0,0 +1,242 @@
+package openairt
+
+import (
+	"context"
+	"github.com/codewandler/openairt-go/events"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"sync"
+	"time"
+)
+
+// instrumentationName is the name of the tracer and meter of the client.
+const instrumentationName = "github.com/codewandler/openairt-go"
+
+// telemetry records the spans and metrics of a client. Without
+// WithTracerProvider and WithMeterProvider it uses no-op providers.
+type telemetry struct {
+	tracer trace.Tracer
+
+	ttfa         metric.Float64Histogram
+	toolDuration metric.Float64Histogram
+	reconnects   metric.Int64Counter
+	dropped      metric.Int64Counter
+	tokens       metric.Int64Counter
+
+	mu         sync.Mutex
+	sessionCtx context.Context
+	session    trace.Span
+	connected  bool // a session was started before
+	responses  map[string]trace.Span
+	tools      map[string]toolSpan
+	// stoppedAt is the end of the last user turn until its first audio
+	stoppedAt time.Time
+}
+
+type toolSpan struct {
+	span  trace.Span
+	start time.Time
+	name  string
+}
+
+func newTelemetry(config *clientConfig) *telemetry {
+	meter := config.meterProvider.Meter(instrumentationName)
+
+	t := &telemetry{
+		tracer:     config.tracerProvider.Tracer(instrumentationName),
+		sessionCtx: context.Background(),
+		responses:  map[string]trace.Span{},
+		tools:      map[string]toolSpan{},
+	}
+
+	// the no-op instruments returned with an error are usable
+	t.ttfa, _ = meter.Float64Histogram("openairt.time_to_first_audio",
+		metric.WithDescription("Time from the end of user speech to the first response audio."),
+		metric.WithUnit("s"))
+	t.toolDuration, _ = meter.Float64Histogram("openairt.tool.duration",
+		metric.WithDescription("Duration of tool calls."),
+		metric.WithUnit("s"))
+	t.reconnects, _ = meter.Int64Counter("openairt.websocket.reconnects",
+		metric.WithDescription("Connections opened by a client after its first one."))
+	t.dropped, _ = meter.Int64Counter("openairt.audio.dropped",
+		metric.WithDescription("Input audio dropped because the input buffer was full."),
+		metric.WithUnit("By"))
+	t.tokens, _ = meter.Int64Counter("openairt.tokens",
+		metric.WithDescription("Tokens used by responses."))
+
+	return t
+}
+
+// ctx returns the context of the session span.
+func (t *telemetry) ctx() context.Context {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.sessionCtx
+}
+
+// startSession starts the span of the session, ending the one of a previous
+// connection.
+func (t *telemetry) startSession(ctx context.Context, model string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.session != nil {
+		t.session.End()
+	}
+	if t.connected {
+		t.reconnects.Add(ctx, 1)
+	}
+	t.connected = true
+	t.sessionCtx, t.session = t.tracer.Start(ctx, "openairt.session",
+		trace.WithAttributes(attribute.String("openairt.model", model)))
+}
+
+// endSession ends the spans still open.
+func (t *telemetry) endSession() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for id, span := range t.responses {
+		span.End()
+		delete(t.responses, id)
+	}
+	for id, ts := range t.tools {
+		ts.span.End()
+		delete(t.tools, id)
+	}
+	if t.session != nil {
+		t.session.End()
+		t.session = nil
+	}
+}
+
+func (t *telemetry) setSessionID(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.session != nil {
+		t.session.SetAttributes(attribute.String("openairt.session.id", id))
+	}
+}
+
+func (t *telemetry) responseCreated(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	_, span := t.tracer.Start(t.sessionCtx, "openairt.response",
+		trace.WithAttributes(attribute.String("openairt.response.id", id)))
+	t.responses[id] = span
+}
+
+func (t *telemetry) responseDone(resp events.ResponseDoneResponse) {
+	if u := resp.Usage; u != nil {
+		for _, c := range []struct {
+			direction, modality string
+			n                   int
+		}{
+			{"input", "text", u.InputTokenDetails.TextTokens},
+			{"input", "audio", u.InputTokenDetails.AudioTokens},
+			{"output", "text", u.OutputTokenDetails.TextTokens},
+			{"output", "audio", u.OutputTokenDetails.AudioTokens},
+		} {
+			t.tokens.Add(t.ctx(), int64(c.n), metric.WithAttributes(
+				attribute.String("openairt.token.direction", c.direction),
+				attribute.String("openairt.token.modality", c.modality),
+			))
+		}
+	}
+
+	t.mu.Lock()
+	span, ok := t.responses[resp.ID]
+	delete(t.responses, resp.ID)
+	t.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	span.SetAttributes(attribute.String("openairt.response.status", resp.Status))
+	if d := resp.StatusDetails; d != nil && d.Reason != "" {
+		span.SetAttributes(attribute.String("openairt.response.status_reason", d.Reason))
+	}
+	if u := resp.Usage; u != nil {
+		span.SetAttributes(
+			attribute.Int("openairt.usage.input_tokens", u.InputTokens),
+			attribute.Int("openairt.usage.output_tokens", u.OutputTokens),
+		)
+	}
+	if resp.Status == "failed" {
+		span.SetStatus(codes.Error, "response failed")
+	}
+	span.End()
+}
+
+// speechStopped starts the measurement of the time to first audio.
+func (t *telemetry) speechStopped() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.stoppedAt = time.Now()
+}
+
+// audioDelta ends the measurement of the time to first audio.
+func (t *telemetry) audioDelta() {
+	t.mu.Lock()
+	stopped, ctx := t.stoppedAt, t.sessionCtx
+	t.stoppedAt = time.Time{}
+	t.mu.Unlock()
+
+	if !stopped.IsZero() {
+		t.ttfa.Record(ctx, time.Since(stopped).Seconds())
+	}
+}
+
+func (t *telemetry) audioDropped(n int64) {
+	t.dropped.Add(t.ctx(), n, metric.WithAttributes(attribute.String("openairt.audio.direction", "input")))
+}
+
+// startTool starts the span of a tool call, as child of the session.
+func (t *telemetry) startTool(callID, name string, argsSize int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	_, span := t.tracer.Start(t.sessionCtx, "openairt.tool "+name, trace.WithAttributes(
+		attribute.String("openairt.tool.name", name),
+		attribute.String("openairt.tool.call_id", callID),
+		attribute.Int("openairt.tool.args_size", argsSize),
+	))
+	t.tools[callID] = toolSpan{span: span, start: time.Now(), name: name}
+}
+
+// toolError records the error of a tool call.
+func (t *telemetry) toolError(callID string, err error) {
+	if err == nil {
+		return
+	}
+
+	t.mu.Lock()
+	ts, ok := t.tools[callID]
+	t.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	ts.span.RecordError(err)
+	ts.span.SetStatus(codes.Error, err.Error())
+}
+
+func (t *telemetry) endTool(callID string) {
+	t.mu.Lock()
+	ts, ok := t.tools[callID]
+	delete(t.tools, callID)
+	t.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	t.toolDuration.Record(t.ctx(), time.Since(ts.start).Seconds(),
+		metric.WithAttributes(attribute.String("openairt.tool.name", ts.name)))
+	ts.span.End()
+}
@@ -0,0 +1,191 @@
+package openairt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/codewandler/openairt-go/tool"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"testing"
+	"time"
+)
+
+// findMetric returns the collected metric with the given name.
+func findMetric(t *testing.T, reader *sdkmetric.ManualReader, name string) metricdata.Metrics {
+	t.Helper()
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == name {
+				return m
+			}
+		}
+	}
+	t.Fatalf("metric %s not found", name)
+	return metricdata.Metrics{}
+}
+
+func spanAttr(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestTelemetry(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	spans := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(spans))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	client, srv := openTestClient(t,
+		WithTracerProvider(tp),
+		WithMeterProvider(mp),
+	)
+	client.OnToolCall(func(name string, args map[string]any) (any, error) {
+		return nil, errors.New("no weather today")
+	})
+
+	require.NoError(t, srv.Send(map[string]any{
+		"type":     "input_audio_buffer.speech_stopped",
+		"event_id": "evt_1",
+		"item_id":  "item_user",
+	}))
+	require.NoError(t, srv.Send(map[string]any{
+		"type":     "response.created",
+		"event_id": "evt_2",
+		"response": map[string]any{"id": "resp_1", "status": "in_progress"},
+	}))
+	sendAudioDelta(t, srv, "item_1", make([]byte, 480))
+	require.NoError(t, srv.Send(usageDone("resp_1", "conv_1", 100, 200)))
+	require.NoError(t, srv.Send(functionCallDone("get_weather", "call_1", `{"city":"Berlin"}`)))
+
+	_, err := srv.Next(ctx, "conversation.item.create")
+	require.NoError(t, err)
+	require.NoError(t, client.Close(ctx))
+
+	byName := map[string]sdktrace.ReadOnlySpan{}
+	for _, s := range spans.GetSpans().Snapshots() {
+		byName[s.Name()] = s
+	}
+	require.Contains(t, byName, "openairt.session")
+	session := byName["openairt.session"]
+	require.Equal(t, "sess_test", spanAttr(session, "openairt.session.id").AsString())
+
+	response := byName["openairt.response"]
+	require.NotNil(t, response)
+	require.Equal(t, session.SpanContext().SpanID(), response.Parent().SpanID())
+	require.Equal(t, "completed", spanAttr(response, "openairt.response.status").AsString())
+	require.EqualValues(t, 200, spanAttr(response, "openairt.usage.output_tokens").AsInt64())
+
+	toolSpan := byName["openairt.tool get_weather"]
+	require.NotNil(t, toolSpan)
+	require.Equal(t, session.SpanContext().SpanID(), toolSpan.Parent().SpanID())
+	require.EqualValues(t, len(`{"city":"Berlin"}`), spanAttr(toolSpan, "openairt.tool.args_size").AsInt64())
+	require.Equal(t, codes.Error, toolSpan.Status().Code)
+
+	ttfa := findMetric(t, reader, "openairt.time_to_first_audio").Data.(metricdata.Histogram[float64])
+	require.EqualValues(t, 1, ttfa.DataPoints[0].Count)
+
+	tokens := findMetric(t, reader, "openairt.tokens").Data.(metricdata.Sum[int64])
+	var total int64
+	for _, dp := range tokens.DataPoints {
+		total += dp.Value
+	}
+	require.EqualValues(t, 20+100+200, total)
+
+	duration := findMetric(t, reader, "openairt.tool.duration").Data.(metricdata.Histogram[float64])
+	require.EqualValues(t, 1, duration.DataPoints[0].Count)
+}
+
+func TestTelemetryConfirmedTool(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	spans := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(spans))
+
+	client, srv := openTestClient(t,
+		WithTracerProvider(tp),
+		WithTools(tool.Tool{Type: "function", Name: "transfer"}),
+		WithToolApprover(func(ctx context.Context, call tool.Call) (tool.Decision, error) {
+			return tool.Confirm("Transfer 100 EUR to Bob?"), nil
+		}),
+	)
+	client.OnToolCall(func(name string, args map[string]any) (any, error) {
+		return map[string]any{"ok": true}, nil
+	})
+
+	toolSpans := func() []sdktrace.ReadOnlySpan {
+		var out []sdktrace.ReadOnlySpan
+		for _, s := range spans.GetSpans().Snapshots() {
+			if s.Name() == "openairt.tool transfer" {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+
+	require.NoError(t, srv.Send(functionCallDone("transfer", "call_1", `{"amount": 100}`)))
+	_, err := srv.Next(ctx, "response.create")
+	require.NoError(t, err)
+
+	// the span stays open while the user is asked
+	require.Empty(t, toolSpans())
+
+	require.NoError(t, srv.Send(userCommitted("item_user")))
+	args, _ := json.Marshal(map[string]any{"confirmation_id": "call_1", "confirmed": true})
+	require.NoError(t, srv.Send(functionCallDone(confirmTool.Name, "call_2", string(args))))
+	_, err = srv.Next(ctx, "response.create")
+	require.NoError(t, err)
+
+	ended := toolSpans()
+	require.Len(t, ended, 1)
+	require.Equal(t, "call_1", spanAttr(ended[0], "openairt.tool.call_id").AsString())
+	require.Equal(t, codes.Unset, ended[0].Status().Code)
+}
+
+func TestTelemetryDroppedAudio(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	client := New(WithKey("test"), WithMeterProvider(mp), WithInputOverflow(OverflowDropNewest), WithInputBuffer(100*time.Millisecond))
+
+	// 100ms at 24 kHz, 4800 bytes, fit the buffer, the rest is dropped
+	_, err := client.InputAudio().Write(make([]byte, 4800+1000))
+	require.NoError(t, err)
+
+	dropped := findMetric(t, reader, "openairt.audio.dropped").Data.(metricdata.Sum[int64])
+	require.EqualValues(t, 1000, dropped.DataPoints[0].Value)
+}
+
+func TestTelemetryReconnects(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	client, _ := openTestClient(t, WithMeterProvider(mp))
+	require.NoError(t, client.Close(ctx))
+
+	// opening the client again counts as reconnect
+	require.NoError(t, client.Open(ctx))
+	t.Cleanup(func() { _ = client.Close(context.Background()) })
+
+	reconnects := findMetric(t, reader, "openairt.websocket.reconnects").Data.(metricdata.Sum[int64])
+	require.EqualValues(t, 1, reconnects.DataPoints[0].Value)
+}
@@ -0,0 +1,89 @@
+package tool
+
+import "errors"
+
+type ErrorClass string
+
+const (
+	// ErrorRetryable is a temporary failure, the model may call the tool again.
+	ErrorRetryable ErrorClass = "retryable"
+	// ErrorUserFacing carries a message meant to be relayed to the user.
+	ErrorUserFacing ErrorClass = "user_facing"
+	// ErrorFatal is a failure that will not go away by retrying.
+	ErrorFatal ErrorClass = "fatal"
+)
+
+// Error is an error returned by a tool call handler that tells the model how
+// to deal with the failure.
+type Error struct {
+	Class   ErrorClass
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	switch {
+	case e.Message != "":
+		return e.Message
+	case e.Err != nil:
+		return e.Err.Error()
+	default:
+		return string(e.Class)
+	}
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// RetryableError marks err as temporary.
+func RetryableError(err error) *Error {
+	return &Error{Class: ErrorRetryable, Err: err}
+}
+
+// UserFacingError returns an error whose message the agent tells the user.
+func UserFacingError(message string) *Error {
+	return &Error{Class: ErrorUserFacing, Message: message}
+}
+
+// FatalError marks err as permanent.
+func FatalError(err error) *Error {
+	return &Error{Class: ErrorFatal, Err: err}
+}
+
+// EnvelopeFunc builds the value the model sees as output of a call. The
+// returned value is marshalled to JSON.
+type EnvelopeFunc func(call Call, result any, err error) any
+
+// DefaultEnvelope returns result as is, {"success": true} for a nil result
+// and {"error": ...} for errors. Errors of type *Error also carry their class
+// and a hint for the model.
+func DefaultEnvelope(call Call, result any, err error) any {
+	if err == nil {
+		if result == nil {
+			return map[string]any{"success": true}
+		}
+		return result
+	}
+
+	var toolErr *Error
+	if !errors.As(err, &toolErr) {
+		return map[string]any{"error": err.Error()}
+	}
+
+	envelope := map[string]any{
+		"error":     toolErr.Error(),
+		"class":     toolErr.Class,
+		"retryable": toolErr.Class == ErrorRetryable,
+	}
+	switch toolErr.Class {
+	case ErrorRetryable:
+		envelope["hint"] = "This is a temporary failure, you may call the tool again."
+	case ErrorUserFacing:
+		envelope["hint"] = "Explain the error to the user."
+	case ErrorFatal:
+		envelope["hint"] = "This will not succeed, do not call the tool again with the same arguments."
+	}
+
+	return envelope
+}
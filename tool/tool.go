@@ -1,5 +1,11 @@
 package tool
 
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
 type Choice string
 
 const (
@@ -7,11 +13,93 @@ const (
 	ChoiceNone Choice = "none"
 )
 
+const (
+	TypeFunction = "function"
+	TypeMCP      = "mcp"
+)
+
 type Tool struct {
 	Type        string     `json:"type"`
 	Name        string     `json:"name"`
 	Description string     `json:"description"`
 	Parameters  Parameters `json:"parameters"`
+
+	// remote MCP server, only used with TypeMCP
+	ServerLabel     string            `json:"server_label,omitempty"`
+	ServerURL       string            `json:"server_url,omitempty"`
+	Headers         map[string]string `json:"headers,omitempty"`
+	AllowedTools    []string          `json:"allowed_tools,omitempty"`
+	RequireApproval *RequireApproval  `json:"require_approval,omitempty"`
+
+	// Holding enables filler speech while a call to this tool is running.
+	Holding *Holding `json:"-"`
+}
+
+// MarshalJSON encodes a function tool as before MCP tools were supported and
+// a remote MCP tool with its server fields only.
+func (t Tool) MarshalJSON() ([]byte, error) {
+	if t.Type != TypeMCP {
+		return json.Marshal(struct {
+			Type        string     `json:"type"`
+			Name        string     `json:"name"`
+			Description string     `json:"description"`
+			Parameters  Parameters `json:"parameters"`
+		}{t.Type, t.Name, t.Description, t.Parameters})
+	}
+
+	return json.Marshal(struct {
+		Type            string            `json:"type"`
+		ServerLabel     string            `json:"server_label"`
+		ServerURL       string            `json:"server_url"`
+		Headers         map[string]string `json:"headers,omitempty"`
+		AllowedTools    []string          `json:"allowed_tools,omitempty"`
+		RequireApproval *RequireApproval  `json:"require_approval,omitempty"`
+	}{t.Type, t.ServerLabel, t.ServerURL, t.Headers, t.AllowedTools, t.RequireApproval})
+}
+
+// Holding makes the agent say something short, e.g. "let me check that for
+// you", when a tool call takes longer than Delay. The filler is generated
+// out-of-band, so it does not become part of the conversation.
+type Holding struct {
+	Delay        time.Duration
+	Instructions string
+}
+
+type ApprovalMode string
+
+const (
+	ApprovalAlways ApprovalMode = "always"
+	ApprovalNever  ApprovalMode = "never"
+)
+
+// RequireApproval configures which tools of a remote MCP server need an
+// approval before the server calls them. Mode applies to all tools, unless
+// Always or Never list tool names explicitly.
+type RequireApproval struct {
+	Mode   ApprovalMode
+	Always []string
+	Never  []string
+}
+
+func (r RequireApproval) MarshalJSON() ([]byte, error) {
+	if len(r.Always) == 0 && len(r.Never) == 0 {
+		return json.Marshal(r.Mode)
+	}
+
+	type toolNames struct {
+		ToolNames []string `json:"tool_names"`
+	}
+	var filter struct {
+		Always *toolNames `json:"always,omitempty"`
+		Never  *toolNames `json:"never,omitempty"`
+	}
+	if len(r.Always) > 0 {
+		filter.Always = &toolNames{ToolNames: r.Always}
+	}
+	if len(r.Never) > 0 {
+		filter.Never = &toolNames{ToolNames: r.Never}
+	}
+	return json.Marshal(filter)
 }
 
 type Parameters struct {
@@ -23,7 +111,53 @@ type Parameters struct {
 type Properties map[string]Property
 
 type Property struct {
-	Type        string `json:"type"`
-	Description string `json:"description,omitempty"`
-	Enum        []any  `json:"enum,omitempty"`
+	Type        string     `json:"type"`
+	Description string     `json:"description,omitempty"`
+	Enum        []any      `json:"enum,omitempty"`
+	Items       *Property  `json:"items,omitempty"`
+	Properties  Properties `json:"properties,omitempty"`
+	Required    []string   `json:"required,omitempty"`
+}
+
+// Call is a function call requested by the model.
+type Call struct {
+	ID        string
+	Name      string
+	Arguments map[string]any
+}
+
+type Verdict string
+
+const (
+	VerdictApprove Verdict = "approve"
+	VerdictDeny    Verdict = "deny"
+	VerdictConfirm Verdict = "confirm"
+)
+
+// Decision is the outcome of a tool call approval.
+type Decision struct {
+	Verdict Verdict
+	// Reason tells the model why a call was denied.
+	Reason string
+	// Prompt is what the agent asks the user to confirm.
+	Prompt string
 }
+
+// Approve lets the call run.
+func Approve() Decision {
+	return Decision{Verdict: VerdictApprove}
+}
+
+// Deny rejects the call. The reason is passed on to the model.
+func Deny(reason string) Decision {
+	return Decision{Verdict: VerdictDeny, Reason: reason}
+}
+
+// Confirm makes the agent ask the user for a verbal confirmation before the
+// call runs.
+func Confirm(prompt string) Decision {
+	return Decision{Verdict: VerdictConfirm, Prompt: prompt}
+}
+
+// Approver decides whether a call may run.
+type Approver func(ctx context.Context, call Call) (Decision, error)
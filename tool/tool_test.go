@@ -0,0 +1,59 @@
+package tool
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestToolJSON(t *testing.T) {
+	for name, tc := range map[string]struct {
+		tool Tool
+		json string
+	}{
+		"function": {
+			tool: Tool{
+				Type:        TypeFunction,
+				Name:        "get_time",
+				Description: "Get current time",
+				Parameters: Parameters{
+					Type:       "object",
+					Properties: Properties{"zone": {Type: "string"}},
+					Required:   []string{"zone"},
+				},
+				Holding: &Holding{Instructions: "never sent"},
+			},
+			json: `{"type": "function", "name": "get_time", "description": "Get current time", "parameters": {"type": "object", "properties": {"zone": {"type": "string"}}, "required": ["zone"]}}`,
+		},
+		"function without parameters": {
+			tool: Tool{Type: TypeFunction, Name: "hang_up", Description: "End the call"},
+			json: `{"type": "function", "name": "hang_up", "description": "End the call", "parameters": {"type": "", "properties": null, "required": null}}`,
+		},
+		"mcp": {
+			tool: Tool{
+				Type:            TypeMCP,
+				ServerLabel:     "crm",
+				ServerURL:       "https://crm.example.com/mcp",
+				Headers:         map[string]string{"Authorization": "Bearer x"},
+				AllowedTools:    []string{"lookup"},
+				RequireApproval: &RequireApproval{Mode: ApprovalNever},
+			},
+			json: `{"type": "mcp", "server_label": "crm", "server_url": "https://crm.example.com/mcp", "headers": {"Authorization": "Bearer x"}, "allowed_tools": ["lookup"], "require_approval": "never"}`,
+		},
+		"mcp with approval filter": {
+			tool: Tool{
+				Type:            TypeMCP,
+				ServerLabel:     "bank",
+				ServerURL:       "https://bank.example.com/mcp",
+				RequireApproval: &RequireApproval{Always: []string{"transfer"}, Never: []string{"balance"}},
+			},
+			json: `{"type": "mcp", "server_label": "bank", "server_url": "https://bank.example.com/mcp", "require_approval": {"always": {"tool_names": ["transfer"]}, "never": {"tool_names": ["balance"]}}}`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			data, err := json.Marshal(tc.tool)
+			require.NoError(t, err)
+			require.JSONEq(t, tc.json, string(data))
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package openairt
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/codewandler/openairt-go/events"
+	"github.com/codewandler/openairt-go/tool"
+	nanoid "github.com/matoous/go-nanoid/v2"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// holdingMetadataKey tags out-of-band filler responses with the call id of
+// the tool call they are covering for.
+const holdingMetadataKey = "openairt_holding_call_id"
+
+// holding tracks the filler response of a single slow tool call.
+type holding struct {
+	timer      *time.Timer
+	sent       bool
+	cancelled  bool
+	responseID string
+}
+
+// handleResponseDone runs the completed function calls of a response. The
+// calls are executed off the read loop, so events (including the audio of a
+// filler response) keep flowing while a handler is busy.
+func (c *Client) handleResponseDone(evt *events.ResponseDoneEvent) {
+	if callID, ok := evt.Response.MetaData[holdingMetadataKey].(string); ok {
+		c.holdingsMu.Lock()
+		delete(c.holdings, callID)
+		c.holdingsMu.Unlock()
+	}
+
+	c.handleMCPOutputs(evt.Response.Output)
+
+	if c.onToolCall == nil {
+		return
+	}
+
+	var calls []events.ResponseDoneOutput
+	for _, o := range evt.Response.Output {
+		if o.Type == "function_call" && o.Status == "completed" {
+			calls = append(calls, o)
+		}
+	}
+	if len(calls) == 0 {
+		return
+	}
+
+	go func() {
+		var instructions []string
+		for _, o := range calls {
+			output, instruction := c.handleFunctionCall(o)
+			if instruction != "" {
+				instructions = append(instructions, instruction)
+			}
+
+			err := c.Send(events.ConversationItemCreateEvent{
+				BaseEvent: events.NewBaseEvent("conversation.item.create"),
+				Item: events.ConversationItem{
+					ID:     o.CallID,
+					Type:   "function_call_output",
+					CallID: o.CallID,
+					Output: output,
+				},
+			})
+			if err != nil {
+				c.log().Error("failed to send tool output", slog.String("response_id", evt.Response.ID), slog.String("item_id", o.ID), slog.String("call_id", o.CallID), slog.Any("err", err))
+			}
+		}
+
+		if len(instructions) > 0 {
+			// confirmations are asked for in a system message, so the
+			// session instructions stay in effect
+			id, _ := nanoid.New()
+			_ = c.Send(events.ConversationItemCreateEvent{
+				BaseEvent: events.NewBaseEvent("conversation.item.create"),
+				Item: events.ConversationItem{
+					ID:   id,
+					Type: "message",
+					Role: "system",
+					Content: []events.ConversationItemContent{
+						{Type: "input_text", Text: strings.Join(instructions, "\n")},
+					},
+				},
+			})
+		}
+
+		if err := c.CreateResponse(); err != nil {
+			c.log().Error("failed to create response to tool outputs", slog.String("response_id", evt.Response.ID), slog.Any("err", err))
+		}
+	}()
+}
+
+// handleFunctionCall runs a single function call through the approval stage
+// and the tool call handler. It returns the output for the model and, if the
+// user has to confirm the call, instructions for the next response.
+func (c *Client) handleFunctionCall(o events.ResponseDoneOutput) (output string, instruction string) {
+	if o.Name == confirmTool.Name && c.config.approver != nil {
+		return c.handleConfirmation(o), ""
+	}
+
+	// the span ends once the call is done, a call waiting for the user to
+	// confirm it stays open
+	c.telemetry.startTool(o.CallID, o.Name, len(o.Arguments))
+
+	call := tool.Call{ID: o.CallID, Name: o.Name}
+	if o.Arguments != "" {
+		if err := json.Unmarshal([]byte(o.Arguments), &call.Arguments); err != nil {
+			err = fmt.Errorf("invalid arguments: %w", err)
+			c.telemetry.toolError(call.ID, err)
+			c.telemetry.endTool(call.ID)
+			return c.toolOutput(call, nil, err), ""
+		}
+	}
+
+	if c.config.approver != nil {
+		return c.approveToolCall(call)
+	}
+
+	return c.callTool(call), ""
+}
+
+// handleResponseCreated remembers the response id of a filler response, or
+// cancels it right away if its tool call has already finished.
+func (c *Client) handleResponseCreated(evt *events.ResponseCreatedEvent) {
+	callID, ok := evt.Response.MetaData[holdingMetadataKey].(string)
+	if !ok {
+		return
+	}
+
+	c.holdingsMu.Lock()
+	h := c.holdings[callID]
+	if h == nil {
+		c.holdingsMu.Unlock()
+		return
+	}
+	if !h.cancelled {
+		h.responseID = evt.Response.ID
+		c.holdingsMu.Unlock()
+		return
+	}
+	delete(c.holdings, callID)
+	c.holdingsMu.Unlock()
+
+	c.cancelResponse(evt.Response.ID)
+}
+
+// callTool invokes the tool call handler and returns the output for the model.
+func (c *Client) callTool(call tool.Call) string {
+	c.startHolding(call)
+	res, err := c.onToolCall(call.Name, call.Arguments)
+	c.stopHolding(call.ID)
+	c.telemetry.toolError(call.ID, err)
+	c.telemetry.endTool(call.ID)
+
+	c.log().Debug("tool call", slog.String("call_id", call.ID), slog.Any("name", call.Name), slog.Any("args", call.Arguments), slog.Any("res", res), slog.Any("err", err))
+
+	return c.toolOutput(call, res, err)
+}
+
+// startHolding schedules the filler response for a tool with a Holding config.
+func (c *Client) startHolding(call tool.Call) {
+	t := c.config.findTool(call.Name)
+	if t == nil || t.Holding == nil {
+		return
+	}
+
+	h := &holding{}
+	c.holdingsMu.Lock()
+	c.holdings[call.ID] = h
+	c.holdingsMu.Unlock()
+
+	instructions := t.Holding.Instructions
+	h.timer = time.AfterFunc(t.Holding.Delay, func() {
+		c.holdingsMu.Lock()
+		if h.cancelled {
+			c.holdingsMu.Unlock()
+			return
+		}
+		h.sent = true
+		c.holdingsMu.Unlock()
+
+		err := c.CreateResponseWithPayload(events.ResponseCreatePayload{
+			Conversation: "none",
+			Instructions: instructions,
+			Modalities:   []string{"text", "audio"},
+			MetaData:     map[string]any{holdingMetadataKey: call.ID},
+		})
+		if err != nil {
+			c.log().Error("failed to create holding response", slog.String("call_id", call.ID), slog.Any("err", err))
+		}
+	})
+}
+
+// stopHolding prevents a pending filler response from being created and
+// cancels it if it is already running.
+func (c *Client) stopHolding(callID string) {
+	c.holdingsMu.Lock()
+	h := c.holdings[callID]
+	if h == nil {
+		c.holdingsMu.Unlock()
+		return
+	}
+
+	h.timer.Stop()
+	h.cancelled = true
+
+	// the filler was requested but the server did not confirm it yet, keep
+	// the entry so handleResponseCreated can cancel it.
+	if h.sent && h.responseID == "" {
+		c.holdingsMu.Unlock()
+		return
+	}
+
+	delete(c.holdings, callID)
+	responseID := h.responseID
+	c.holdingsMu.Unlock()
+
+	if responseID != "" {
+		c.cancelResponse(responseID)
+	}
+}
+
+func (c *Client) cancelResponse(responseID string) {
+	if err := c.Send(events.ResponseCancelEvent{
+		BaseEvent:  events.NewBaseEvent("response.cancel"),
+		ResponseID: responseID,
+	}); err != nil {
+		c.log().Error("failed to cancel response", slog.String("response_id", responseID), slog.Any("err", err))
+	}
+}
+
+// toolOutput renders the result of a tool call for the model.
+func (c *Client) toolOutput(call tool.Call, res any, err error) string {
+	data, mErr := marshalJSON(c.config.envelope(call, res, err))
+	if mErr != nil {
+		c.log().Error("failed to encode tool output", slog.String("name", call.Name), slog.String("call_id", call.ID), slog.Any("err", mErr))
+
+		data, mErr = marshalJSON(c.config.envelope(call, nil, tool.FatalError(fmt.Errorf("failed to encode tool result: %w", mErr))))
+		if mErr != nil {
+			data, _ = marshalJSON(map[string]any{"error": "failed to encode tool result"})
+		}
+	}
+
+	return truncateToolOutput(string(data), c.config.toolOutput)
+}
@@ -0,0 +1,197 @@
+package openairt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/codewandler/openairt-go/events"
+	"github.com/codewandler/openairt-go/openairttest"
+	"github.com/codewandler/openairt-go/tool"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+func openTestClient(t *testing.T, opts ...ClientOption) (*Client, *openairttest.Server) {
+	t.Helper()
+
+	srv := openairttest.NewServer()
+	t.Cleanup(srv.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	client := New(append([]ClientOption{WithKey("test"), WithBaseURL(srv.URL)}, opts...)...)
+	require.NoError(t, client.Open(ctx))
+
+	return client, srv
+}
+
+func functionCallDone(name, callID, args string) map[string]any {
+	return map[string]any{
+		"type":     "response.done",
+		"event_id": "evt_" + callID,
+		"response": map[string]any{
+			"id":     "resp_" + callID,
+			"status": "completed",
+			"output": []map[string]any{{
+				"type":      "function_call",
+				"status":    "completed",
+				"name":      name,
+				"call_id":   callID,
+				"arguments": args,
+			}},
+		},
+	}
+}
+
+func TestToolHolding(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, srv := openTestClient(t, WithTools(tool.Tool{
+		Type: "function",
+		Name: "slow",
+		Holding: &tool.Holding{
+			Delay:        10 * time.Millisecond,
+			Instructions: "Tell the user you are checking.",
+		},
+	}))
+
+	release := make(chan struct{})
+	client.OnToolCall(func(name string, args map[string]any) (any, error) {
+		<-release
+		return "done", nil
+	})
+
+	require.NoError(t, srv.Send(functionCallDone("slow", "call_1", "{}")))
+
+	evt, err := srv.Next(ctx, "response.create")
+	require.NoError(t, err)
+	var filler events.ResponseCreateEvent
+	require.NoError(t, evt.Decode(&filler))
+	require.Equal(t, "none", filler.Response.Conversation)
+	require.Equal(t, "Tell the user you are checking.", filler.Response.Instructions)
+	require.Equal(t, "call_1", filler.Response.MetaData[holdingMetadataKey])
+
+	require.NoError(t, srv.Send(map[string]any{
+		"type":     "response.created",
+		"event_id": "evt_created",
+		"response": map[string]any{
+			"id":       "resp_filler",
+			"status":   "in_progress",
+			"metadata": map[string]any{holdingMetadataKey: "call_1"},
+		},
+	}))
+
+	// make sure the created event was handled before the tool finishes
+	require.Eventually(t, func() bool {
+		client.holdingsMu.Lock()
+		defer client.holdingsMu.Unlock()
+		h := client.holdings["call_1"]
+		return h != nil && h.responseID == "resp_filler"
+	}, time.Second, time.Millisecond)
+
+	close(release)
+
+	evt, err = srv.Next(ctx, "response.cancel")
+	require.NoError(t, err)
+	var cancelEvt events.ResponseCancelEvent
+	require.NoError(t, evt.Decode(&cancelEvt))
+	require.Equal(t, "resp_filler", cancelEvt.ResponseID)
+
+	evt, err = srv.Next(ctx, "conversation.item.create")
+	require.NoError(t, err)
+	var output events.ConversationItemCreateEvent
+	require.NoError(t, evt.Decode(&output))
+	require.Equal(t, "function_call_output", output.Item.Type)
+	require.Equal(t, `"done"`, output.Item.Output)
+
+	_, err = srv.Next(ctx, "response.create")
+	require.NoError(t, err)
+}
+
+func TestToolHoldingNotNeeded(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, srv := openTestClient(t, WithTools(tool.Tool{
+		Type:    "function",
+		Name:    "fast",
+		Holding: &tool.Holding{Delay: time.Hour},
+	}))
+	client.OnToolCall(func(name string, args map[string]any) (any, error) {
+		return nil, nil
+	})
+
+	require.NoError(t, srv.Send(functionCallDone("fast", "call_1", "{}")))
+
+	_, err := srv.Next(ctx, "conversation.item.create")
+	require.NoError(t, err)
+	evt, err := srv.Next(ctx, "response.create")
+	require.NoError(t, err)
+
+	var create events.ResponseCreateEvent
+	require.NoError(t, evt.Decode(&create))
+	require.Empty(t, create.Response.Conversation)
+
+	client.holdingsMu.Lock()
+	defer client.holdingsMu.Unlock()
+	require.Empty(t, client.holdings)
+}
+
+func TestToolOutputEnvelope(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, srv := openTestClient(t,
+		WithToolOutputLimit(200),
+		WithToolEnvelope(func(call tool.Call, result any, err error) any {
+			if err != nil {
+				return tool.DefaultEnvelope(call, result, err)
+			}
+			return map[string]any{"tool": call.Name, "result": result}
+		}),
+	)
+	client.OnToolCall(func(name string, args map[string]any) (any, error) {
+		switch name {
+		case "flaky":
+			return nil, tool.RetryableError(errors.New("database timeout"))
+		default:
+			return strings.Split(strings.Repeat("row ", 200), " "), nil
+		}
+	})
+
+	require.NoError(t, srv.Send(functionCallDone("flaky", "call_1", "{}")))
+
+	evt, err := srv.Next(ctx, "conversation.item.create")
+	require.NoError(t, err)
+	var output events.ConversationItemCreateEvent
+	require.NoError(t, evt.Decode(&output))
+
+	var envelope map[string]any
+	require.NoError(t, json.Unmarshal([]byte(output.Item.Output), &envelope))
+	require.Equal(t, "database timeout", envelope["error"])
+	require.Equal(t, "retryable", envelope["class"])
+	require.Equal(t, true, envelope["retryable"])
+
+	require.NoError(t, srv.Send(functionCallDone("query", "call_2", "{}")))
+
+	evt, err = srv.Next(ctx, "conversation.item.create")
+	require.NoError(t, err)
+	require.NoError(t, evt.Decode(&output))
+	require.LessOrEqual(t, len(output.Item.Output), 200)
+	var truncated map[string]any
+	require.NoError(t, json.Unmarshal([]byte(output.Item.Output), &truncated))
+	require.Equal(t, "query", truncated["tool"])
+	require.Equal(t, "result", truncated["truncated_field"])
+}
+
+func TestToolOutputOptions(t *testing.T) {
+	client := New(WithKey("test"), WithToolEnvelope(nil))
+	require.NotNil(t, client.config.envelope)
+
+	client = New(WithKey("test"), WithToolOutputLimit(10))
+	require.ErrorContains(t, client.Open(t.Context()), "tool output limit too small")
+}
@@ -0,0 +1,160 @@
+package openairt
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// truncatedMarker is the output of a tool call when not even the start of it
+// fits into the limit.
+const truncatedMarker = `{"truncated":true}`
+
+// truncateToolOutput shrinks a JSON encoded tool output to at most max bytes.
+// Arrays, also when they are a field of an object, are cut to the items that
+// fit, so the model still gets well-formed data. Everything else is cut as
+// text. The result always tells the model that it was truncated. The client
+// rejects limits below the size of {"truncated":true}, here they yield it.
+func truncateToolOutput(output string, max int) string {
+	if max <= 0 || len(output) <= max {
+		return output
+	}
+	if max < len(truncatedMarker) {
+		max = len(truncatedMarker)
+	}
+
+	dec := json.NewDecoder(strings.NewReader(output))
+	dec.UseNumber()
+
+	var v any
+	if err := dec.Decode(&v); err == nil {
+		if s, ok := truncateJSON(v, max); ok {
+			return s
+		}
+	}
+
+	return truncateText(output, max)
+}
+
+func truncateJSON(v any, max int) (string, bool) {
+	switch x := v.(type) {
+	case []any:
+		return fitItems(len(x), max, func(n int) any {
+			return map[string]any{
+				"truncated":   true,
+				"total_items": len(x),
+				"items":       x[:n],
+			}
+		})
+	case map[string]any:
+		// cut the largest array field
+		var (
+			field string
+			size  int
+		)
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if _, ok := x[k].([]any); !ok {
+				continue
+			}
+			if d, err := marshalJSON(x[k]); err == nil && len(d) > size {
+				field, size = k, len(d)
+			}
+		}
+		if field == "" {
+			return "", false
+		}
+
+		items := x[field].([]any)
+		return fitItems(len(items), max, func(n int) any {
+			m := make(map[string]any, len(x)+3)
+			for k, v := range x {
+				m[k] = v
+			}
+			m[field] = items[:n]
+			m["truncated"] = true
+			m["truncated_field"] = field
+			m["total_items"] = len(items)
+			return m
+		})
+	default:
+		return "", false
+	}
+}
+
+// fitItems finds the largest n for which build(n) encodes to at most max bytes.
+func fitItems(total int, max int, build func(n int) any) (string, bool) {
+	encode := func(n int) ([]byte, bool) {
+		d, err := marshalJSON(build(n))
+		return d, err == nil && len(d) <= max
+	}
+
+	best, ok := encode(0)
+	if !ok {
+		return "", false
+	}
+
+	lo, hi := 1, total-1
+	for lo <= hi {
+		mid := (lo + hi) / 2
+		if d, ok := encode(mid); ok {
+			best = d
+			lo = mid + 1
+		} else {
+			hi = mid - 1
+		}
+	}
+
+	return string(best), true
+}
+
+func truncateText(output string, max int) string {
+	build := func(n int) []byte {
+		d, _ := marshalJSON(map[string]any{
+			"truncated":      true,
+			"original_bytes": len(output),
+			"content":        output[:n],
+		})
+		return d
+	}
+
+	best := build(0)
+	if len(best) > max {
+		return truncatedMarker
+	}
+
+	lo, hi := 1, len(output)-1
+	for lo <= hi {
+		mid := (lo + hi) / 2
+		n := mid
+		for n > 0 && !utf8.RuneStart(output[n]) {
+			n--
+		}
+		if d := build(n); len(d) <= max {
+			best = d
+			lo = mid + 1
+		} else {
+			hi = mid - 1
+		}
+	}
+
+	return string(best)
+}
+
+// marshalJSON encodes v without escaping HTML, which would only inflate the
+// output the model sees.
+func marshalJSON(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
@@ -0,0 +1,75 @@
+package openairt
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestTruncateToolOutput(t *testing.T) {
+	rows := make([]map[string]any, 100)
+	for i := range rows {
+		rows[i] = map[string]any{"id": i, "name": fmt.Sprintf("customer %d", i)}
+	}
+	list, _ := json.Marshal(rows)
+	object, _ := json.Marshal(map[string]any{"query": "customers", "rows": rows})
+
+	t.Run("within limit", func(t *testing.T) {
+		require.Equal(t, `{"ok":true}`, truncateToolOutput(`{"ok":true}`, 100))
+		require.Equal(t, string(list), truncateToolOutput(string(list), 0))
+	})
+
+	t.Run("array", func(t *testing.T) {
+		out := truncateToolOutput(string(list), 500)
+		require.LessOrEqual(t, len(out), 500)
+
+		var v struct {
+			Truncated  bool             `json:"truncated"`
+			TotalItems int              `json:"total_items"`
+			Items      []map[string]any `json:"items"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(out), &v))
+		require.True(t, v.Truncated)
+		require.Equal(t, 100, v.TotalItems)
+		require.NotEmpty(t, v.Items)
+		require.Less(t, len(v.Items), 100)
+	})
+
+	t.Run("array field", func(t *testing.T) {
+		out := truncateToolOutput(string(object), 500)
+		require.LessOrEqual(t, len(out), 500)
+
+		var v map[string]any
+		require.NoError(t, json.Unmarshal([]byte(out), &v))
+		require.Equal(t, "customers", v["query"])
+		require.Equal(t, "rows", v["truncated_field"])
+		require.Equal(t, float64(100), v["total_items"])
+		require.NotEmpty(t, v["rows"])
+	})
+
+	t.Run("text", func(t *testing.T) {
+		text, _ := json.Marshal(strings.Repeat("äbc ", 1000))
+		out := truncateToolOutput(string(text), 200)
+		require.LessOrEqual(t, len(out), 200)
+
+		var v struct {
+			Truncated     bool   `json:"truncated"`
+			OriginalBytes int    `json:"original_bytes"`
+			Content       string `json:"content"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(out), &v))
+		require.True(t, v.Truncated)
+		require.Equal(t, len(text), v.OriginalBytes)
+		require.True(t, strings.HasPrefix(string(text), v.Content))
+	})
+
+	t.Run("tiny limit", func(t *testing.T) {
+		text, _ := json.Marshal(strings.Repeat("äbc ", 1000))
+		for _, max := range []int{1, 18, 40} {
+			out := truncateToolOutput(string(text), max)
+			require.Equal(t, `{"truncated":true}`, out)
+		}
+	})
+}
@@ -0,0 +1,203 @@
+package openairt
+
+import (
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+// TurnStats are the timestamps of a user turn and its response. Timestamps of
+// steps that did not happen are zero.
+type TurnStats struct {
+	// ItemID is the user message, ResponseID the response to it.
+	ItemID     string
+	ResponseID string
+
+	SpeechStopped   time.Time
+	Committed       time.Time
+	ResponseCreated time.Time
+	FirstAudio      time.Time
+	FirstTranscript time.Time
+	Done            time.Time
+}
+
+// since returns the time from the end of speech to t, zero if t is unknown.
+func (s TurnStats) since(t time.Time) time.Duration {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Sub(s.SpeechStopped)
+}
+
+// VoiceToVoice returns the time from the end of speech to the first audio of
+// the response.
+func (s TurnStats) VoiceToVoice() time.Duration {
+	return s.since(s.FirstAudio)
+}
+
+// TurnLatency are the latencies of the steps of a turn from the end of the
+// user's speech.
+type TurnLatency struct {
+	Committed       time.Duration
+	ResponseCreated time.Duration
+	FirstAudio      time.Duration
+	FirstTranscript time.Duration
+	Done            time.Duration
+}
+
+func (s TurnStats) latency() TurnLatency {
+	return TurnLatency{
+		Committed:       s.since(s.Committed),
+		ResponseCreated: s.since(s.ResponseCreated),
+		FirstAudio:      s.since(s.FirstAudio),
+		FirstTranscript: s.since(s.FirstTranscript),
+		Done:            s.since(s.Done),
+	}
+}
+
+// turnTracker follows the current turn and keeps the latencies of the last
+// turns.
+type turnTracker struct {
+	mu      sync.Mutex
+	current *TurnStats
+	window  []TurnLatency
+	next    int // position of the next latency in a full window
+	size    int
+	onTurn  func(TurnStats)
+}
+
+func newTurnTracker(size int) *turnTracker {
+	return &turnTracker{size: max(1, size)}
+}
+
+// speechStopped starts a turn, discarding the one in progress.
+func (t *turnTracker) speechStopped(itemID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.current = &TurnStats{ItemID: itemID, SpeechStopped: time.Now()}
+}
+
+func (t *turnTracker) committed(itemID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.current != nil && t.current.Committed.IsZero() {
+		t.current.Committed = time.Now()
+		if t.current.ItemID == "" {
+			t.current.ItemID = itemID
+		}
+	}
+}
+
+// responseCreated assigns the first response after the end of speech to the
+// turn. Filler responses outside the conversation, see tool.Holding, are not
+// the answer and are skipped.
+func (t *turnTracker) responseCreated(responseID string, filler bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if filler || t.current == nil || t.current.ResponseID != "" {
+		return
+	}
+	t.current.ResponseID = responseID
+	if t.current.ResponseCreated.IsZero() {
+		t.current.ResponseCreated = time.Now()
+	}
+}
+
+func (t *turnTracker) audioDelta(responseID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.current != nil && t.current.ResponseID == responseID && t.current.FirstAudio.IsZero() {
+		t.current.FirstAudio = time.Now()
+	}
+}
+
+func (t *turnTracker) transcriptDelta(responseID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.current != nil && t.current.ResponseID == responseID && t.current.FirstTranscript.IsZero() {
+		t.current.FirstTranscript = time.Now()
+	}
+}
+
+// responseDone completes the turn of the response. A response that only
+// called tools leaves the turn open for the response to the tool outputs.
+func (t *turnTracker) responseDone(responseID string, toolCalls bool) {
+	t.mu.Lock()
+	if t.current == nil || t.current.ResponseID != responseID {
+		t.mu.Unlock()
+		return
+	}
+	if toolCalls && t.current.FirstAudio.IsZero() && t.current.FirstTranscript.IsZero() {
+		t.current.ResponseID = ""
+		t.mu.Unlock()
+		return
+	}
+
+	stats := *t.current
+	stats.Done = time.Now()
+	t.current = nil
+
+	if len(t.window) < t.size {
+		t.window = append(t.window, stats.latency())
+	} else {
+		t.window[t.next] = stats.latency()
+		t.next = (t.next + 1) % t.size
+	}
+	onTurn := t.onTurn
+	t.mu.Unlock()
+
+	if onTurn != nil {
+		onTurn(stats)
+	}
+}
+
+// percentile returns the latencies at quantile q of the turns in the window.
+func (t *turnTracker) percentile(q float64) TurnLatency {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	pick := func(f func(TurnLatency) time.Duration) time.Duration {
+		var values []time.Duration
+		for _, l := range t.window {
+			if v := f(l); v > 0 {
+				values = append(values, v)
+			}
+		}
+		if len(values) == 0 {
+			return 0
+		}
+		slices.Sort(values)
+
+		// nearest rank
+		i := int(math.Ceil(q*float64(len(values)))) - 1
+		return values[min(max(i, 0), len(values)-1)]
+	}
+
+	return TurnLatency{
+		Committed:       pick(func(l TurnLatency) time.Duration { return l.Committed }),
+		ResponseCreated: pick(func(l TurnLatency) time.Duration { return l.ResponseCreated }),
+		FirstAudio:      pick(func(l TurnLatency) time.Duration { return l.FirstAudio }),
+		FirstTranscript: pick(func(l TurnLatency) time.Duration { return l.FirstTranscript }),
+		Done:            pick(func(l TurnLatency) time.Duration { return l.Done }),
+	}
+}
+
+// OnTurnStats sets a handler called with the stats of every completed turn.
+func (c *Client) OnTurnStats(h func(TurnStats)) {
+	c.turns.mu.Lock()
+	defer c.turns.mu.Unlock()
+
+	c.turns.onTurn = h
+}
+
+// TurnLatency returns the latencies at quantile q, e.g. 0.95, over the last
+// turns, see WithTurnWindow. Steps no turn reached are zero.
+func (c *Client) TurnLatency(q float64) TurnLatency {
+	return c.turns.percentile(q)
+}
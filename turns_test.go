@@ -0,0 +1,152 @@
+package openairt
+
+import (
+	"context"
+	"encoding/base64"
+	"github.com/codewandler/openairt-go/events"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestTurnStats(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, srv := openTestClient(t, WithAudioFormat(events.AudioFormatG711ULaw))
+
+	turns := make(chan TurnStats, 1)
+	client.OnTurnStats(func(s TurnStats) {
+		turns <- s
+	})
+
+	send := func(evt map[string]any) {
+		require.NoError(t, srv.Send(evt))
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	send(map[string]any{"type": "input_audio_buffer.speech_stopped", "event_id": "evt_1", "item_id": "item_user"})
+	send(map[string]any{"type": "input_audio_buffer.committed", "event_id": "evt_2", "item_id": "item_user"})
+	send(map[string]any{
+		"type":     "response.created",
+		"event_id": "evt_3",
+		"response": map[string]any{"id": "resp_1", "status": "in_progress"},
+	})
+	send(map[string]any{
+		"type":        "response.audio_transcript.delta",
+		"event_id":    "evt_4",
+		"response_id": "resp_1",
+		"delta":       "Hi",
+	})
+	sendAudioDelta(t, srv, "item_1", make([]byte, 160))
+	time.Sleep(10 * time.Millisecond)
+	send(map[string]any{
+		"type":     "response.done",
+		"event_id": "evt_5",
+		"response": map[string]any{"id": "resp_1", "status": "completed"},
+	})
+
+	var s TurnStats
+	select {
+	case s = <-turns:
+	case <-ctx.Done():
+		t.Fatal("no turn stats")
+	}
+
+	require.Equal(t, "item_user", s.ItemID)
+	require.Equal(t, "resp_1", s.ResponseID)
+	steps := []time.Time{s.SpeechStopped, s.Committed, s.ResponseCreated, s.FirstTranscript, s.FirstAudio, s.Done}
+	for i := 1; i < len(steps); i++ {
+		require.True(t, steps[i].After(steps[i-1]), "step %d", i)
+	}
+	require.Equal(t, s.FirstAudio.Sub(s.SpeechStopped), s.VoiceToVoice())
+
+	latency := client.TurnLatency(0.5)
+	require.Equal(t, s.VoiceToVoice(), latency.FirstAudio)
+	require.Equal(t, s.Done.Sub(s.SpeechStopped), latency.Done)
+}
+
+func TestTurnStatsToolCall(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, srv := openTestClient(t, WithAudioFormat(events.AudioFormatG711ULaw))
+
+	turns := make(chan TurnStats, 1)
+	client.OnTurnStats(func(s TurnStats) {
+		turns <- s
+	})
+
+	send := func(evt map[string]any) {
+		require.NoError(t, srv.Send(evt))
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	send(map[string]any{"type": "input_audio_buffer.speech_stopped", "event_id": "evt_1", "item_id": "item_user"})
+	send(map[string]any{
+		"type":     "response.created",
+		"event_id": "evt_2",
+		"response": map[string]any{"id": "resp_call", "status": "in_progress"},
+	})
+	// the first response only calls a tool, the filler while it runs is not
+	// the answer
+	send(map[string]any{
+		"type":     "response.done",
+		"event_id": "evt_3",
+		"response": map[string]any{"id": "resp_call", "status": "completed", "output": []map[string]any{{
+			"type": "function_call", "status": "completed", "name": "lookup", "call_id": "call_1",
+		}}},
+	})
+	send(map[string]any{
+		"type":     "response.created",
+		"event_id": "evt_4",
+		"response": map[string]any{"id": "resp_filler", "status": "in_progress", "metadata": map[string]any{holdingMetadataKey: "call_1"}},
+	})
+	send(map[string]any{
+		"type":     "response.created",
+		"event_id": "evt_5",
+		"response": map[string]any{"id": "resp_answer", "status": "in_progress"},
+	})
+	send(map[string]any{
+		"type":        "response.audio.delta",
+		"event_id":    "evt_delta",
+		"response_id": "resp_answer",
+		"item_id":     "item_1",
+		"delta":       base64.StdEncoding.EncodeToString(make([]byte, 160)),
+	})
+	require.Empty(t, turns)
+	send(map[string]any{
+		"type":     "response.done",
+		"event_id": "evt_6",
+		"response": map[string]any{"id": "resp_answer", "status": "completed"},
+	})
+
+	var s TurnStats
+	select {
+	case s = <-turns:
+	case <-ctx.Done():
+		t.Fatal("no turn stats")
+	}
+	require.Equal(t, "resp_answer", s.ResponseID)
+	require.True(t, s.ResponseCreated.Before(s.FirstAudio))
+	require.NotZero(t, s.VoiceToVoice())
+}
+
+func TestTurnLatencyPercentile(t *testing.T) {
+	tracker := newTurnTracker(10)
+	// the window keeps the last 10 of 15 turns, 6..15ms
+	for i := 1; i <= 15; i++ {
+		stats := TurnStats{SpeechStopped: time.Unix(0, 0)}
+		stats.FirstAudio = stats.SpeechStopped.Add(time.Duration(i) * time.Millisecond)
+		stats.ResponseID = "resp"
+		tracker.current = &stats
+		tracker.responseDone("resp", false)
+	}
+
+	require.Len(t, tracker.window, 10)
+	require.Equal(t, 10*time.Millisecond, tracker.percentile(0.5).FirstAudio)
+	require.Equal(t, 15*time.Millisecond, tracker.percentile(0.95).FirstAudio)
+	require.Equal(t, 6*time.Millisecond, tracker.percentile(0).FirstAudio)
+	// no turn had a transcript
+	require.Zero(t, tracker.percentile(0.5).FirstTranscript)
+}
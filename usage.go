@@ -0,0 +1,219 @@
+package openairt
+
+import (
+	"errors"
+	"github.com/codewandler/openairt-go/events"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// ErrBudgetExceeded is returned when a response is requested after the cost
+// of the session exceeded the budget set with WithBudget.
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// Prices are the prices of a model in USD per million tokens.
+type Prices struct {
+	InputText        float64
+	CachedInputText  float64
+	InputAudio       float64
+	CachedInputAudio float64
+	OutputText       float64
+	OutputAudio      float64
+}
+
+// PriceTable maps model names to their prices. A model without an entry uses
+// the entry with the longest name that is a prefix of it, so dated versions
+// share the price of their model.
+type PriceTable map[string]Prices
+
+// DefaultPrices are the list prices of the realtime models as of mid 2025.
+// They are estimates; pass your own with WithPrices.
+var DefaultPrices = PriceTable{
+	"gpt-4o-realtime-preview": {
+		InputText: 5, CachedInputText: 2.5, InputAudio: 40, CachedInputAudio: 2.5,
+		OutputText: 20, OutputAudio: 80,
+	},
+	"gpt-4o-mini-realtime-preview": {
+		InputText: 0.6, CachedInputText: 0.3, InputAudio: 10, CachedInputAudio: 0.3,
+		OutputText: 2.4, OutputAudio: 20,
+	},
+	"gpt-realtime": {
+		InputText: 4, CachedInputText: 0.4, InputAudio: 32, CachedInputAudio: 0.4,
+		OutputText: 16, OutputAudio: 64,
+	},
+}
+
+// Lookup returns the prices of a model.
+func (t PriceTable) Lookup(model string) (Prices, bool) {
+	if p, ok := t[model]; ok {
+		return p, true
+	}
+
+	var (
+		best  string
+		found bool
+	)
+	for name := range t {
+		if strings.HasPrefix(model, name) && len(name) > len(best) {
+			best, found = name, true
+		}
+	}
+	return t[best], found
+}
+
+// Cost returns the cost of token usage in USD.
+func (p Prices) Cost(u events.Usage) float64 {
+	in := u.InputTokenDetails
+	cached := in.CachedTokensDetails
+
+	cost := float64(in.TextTokens-cached.TextTokens)*p.InputText +
+		float64(cached.TextTokens)*p.CachedInputText +
+		float64(in.AudioTokens-cached.AudioTokens)*p.InputAudio +
+		float64(cached.AudioTokens)*p.CachedInputAudio +
+		float64(u.OutputTokenDetails.TextTokens)*p.OutputText +
+		float64(u.OutputTokenDetails.AudioTokens)*p.OutputAudio
+
+	return cost / 1_000_000
+}
+
+// Usage is the accumulated token usage of responses.
+type Usage struct {
+	Responses         int
+	InputTextTokens   int
+	InputAudioTokens  int
+	CachedTextTokens  int
+	CachedAudioTokens int
+	OutputTextTokens  int
+	OutputAudioTokens int
+	// Cost is the estimated cost in USD, zero if the model has no prices.
+	Cost float64
+}
+
+// InputTokens returns all input tokens, including the cached ones.
+func (u Usage) InputTokens() int {
+	return u.InputTextTokens + u.InputAudioTokens
+}
+
+// OutputTokens returns all output tokens.
+func (u Usage) OutputTokens() int {
+	return u.OutputTextTokens + u.OutputAudioTokens
+}
+
+func (u *Usage) add(e events.Usage, cost float64) {
+	u.Responses++
+	u.InputTextTokens += e.InputTokenDetails.TextTokens
+	u.InputAudioTokens += e.InputTokenDetails.AudioTokens
+	u.CachedTextTokens += e.InputTokenDetails.CachedTokensDetails.TextTokens
+	u.CachedAudioTokens += e.InputTokenDetails.CachedTokensDetails.AudioTokens
+	u.OutputTextTokens += e.OutputTokenDetails.TextTokens
+	u.OutputAudioTokens += e.OutputTokenDetails.AudioTokens
+	u.Cost += cost
+}
+
+// UsageSnapshot is the usage of a client, see Client.Usage.
+type UsageSnapshot struct {
+	Total Usage
+	// Conversations holds the usage per conversation ID.
+	Conversations map[string]Usage
+}
+
+// BudgetExceededEvent is dispatched to OnEvent once the cost of the session
+// exceeds the budget set with WithBudget.
+type BudgetExceededEvent struct {
+	Cost   float64
+	Budget float64
+}
+
+// usageTracker accumulates the usage of the responses of a client.
+type usageTracker struct {
+	mu            sync.Mutex
+	prices        Prices
+	hasPrices     bool
+	budget        float64
+	total         Usage
+	conversations map[string]Usage
+	exceeded      bool
+}
+
+func newUsageTracker(config *clientConfig) *usageTracker {
+	prices, ok := config.prices.Lookup(config.model)
+	return &usageTracker{
+		prices:        prices,
+		hasPrices:     ok,
+		budget:        config.budget,
+		conversations: map[string]Usage{},
+	}
+}
+
+// add records the usage of a response. It reports whether this exceeded the
+// budget.
+func (t *usageTracker) add(resp events.ResponseDoneResponse) bool {
+	if resp.Usage == nil {
+		return false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var cost float64
+	if t.hasPrices {
+		cost = t.prices.Cost(*resp.Usage)
+	}
+
+	t.total.add(*resp.Usage, cost)
+	if resp.ConversationID != "" {
+		u := t.conversations[resp.ConversationID]
+		u.add(*resp.Usage, cost)
+		t.conversations[resp.ConversationID] = u
+	}
+
+	if t.budget > 0 && !t.exceeded && t.total.Cost > t.budget {
+		t.exceeded = true
+		return true
+	}
+	return false
+}
+
+func (t *usageTracker) overBudget() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.exceeded
+}
+
+func (t *usageTracker) snapshot() UsageSnapshot {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s := UsageSnapshot{
+		Total:         t.total,
+		Conversations: make(map[string]Usage, len(t.conversations)),
+	}
+	for id, u := range t.conversations {
+		s.Conversations[id] = u
+	}
+	return s
+}
+
+// Usage returns the token usage and estimated cost of the responses so far.
+func (c *Client) Usage() UsageSnapshot {
+	return c.usage.snapshot()
+}
+
+// handleUsage records the usage of a finished response and enforces the
+// budget.
+func (c *Client) handleUsage(resp events.ResponseDoneResponse) {
+	if !c.usage.add(resp) {
+		return
+	}
+
+	snapshot := c.usage.snapshot()
+	c.log().Warn("budget exceeded, cancelling responses", slog.String("response_id", resp.ID),
+		slog.Float64("cost", snapshot.Total.Cost), slog.Float64("budget", c.config.budget))
+	c.cancelActiveResponses()
+
+	if c.onEvent != nil {
+		c.onEvent(&BudgetExceededEvent{Cost: snapshot.Total.Cost, Budget: c.config.budget})
+	}
+}
@@ -0,0 +1,144 @@
+package openairt
+
+import (
+	"context"
+	"github.com/codewandler/openairt-go/events"
+	"github.com/codewandler/openairt-go/openairttest"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func usageDone(responseID, conversationID string, inAudio, outAudio int) map[string]any {
+	return map[string]any{
+		"type":     "response.done",
+		"event_id": "evt_" + responseID,
+		"response": map[string]any{
+			"id":              responseID,
+			"conversation_id": conversationID,
+			"status":          "completed",
+			"output":          []any{},
+			"usage": map[string]any{
+				"total_tokens":  inAudio + 20 + outAudio,
+				"input_tokens":  inAudio + 20,
+				"output_tokens": outAudio,
+				"input_token_details": map[string]any{
+					"cached_tokens": 10,
+					"text_tokens":   20,
+					"audio_tokens":  inAudio,
+					"cached_tokens_details": map[string]any{
+						"text_tokens":  10,
+						"audio_tokens": 0,
+					},
+				},
+				"output_token_details": map[string]any{
+					"text_tokens":  0,
+					"audio_tokens": outAudio,
+				},
+			},
+		},
+	}
+}
+
+func TestPriceTableLookup(t *testing.T) {
+	p, ok := DefaultPrices.Lookup("gpt-4o-realtime-preview-2025-06-03")
+	require.True(t, ok)
+	require.Equal(t, DefaultPrices["gpt-4o-realtime-preview"], p)
+
+	p, ok = DefaultPrices.Lookup("gpt-4o-mini-realtime-preview-2024-12-17")
+	require.True(t, ok)
+	require.Equal(t, DefaultPrices["gpt-4o-mini-realtime-preview"], p)
+
+	_, ok = DefaultPrices.Lookup("whisper-1")
+	require.False(t, ok)
+}
+
+func TestPricesCost(t *testing.T) {
+	prices := Prices{InputText: 1, CachedInputText: 0.5, InputAudio: 10, OutputText: 2, OutputAudio: 20}
+
+	cost := prices.Cost(events.Usage{
+		InputTokenDetails: events.InputTokenDetails{
+			TextTokens:          1_000_000,
+			AudioTokens:         1_000_000,
+			CachedTokensDetails: events.CachedTokensDetails{TextTokens: 500_000},
+		},
+		OutputTokenDetails: events.OutputTokenDetails{AudioTokens: 500_000},
+	})
+	require.InDelta(t, 0.5+0.25+10+10, cost, 1e-9)
+}
+
+func TestUsage(t *testing.T) {
+	client, srv := openTestClient(t, WithModel("test-model"), WithPrices(PriceTable{
+		"test-model": {InputText: 1, CachedInputText: 0.5, InputAudio: 10, OutputAudio: 20},
+	}))
+
+	require.NoError(t, srv.Send(usageDone("resp_1", "conv_1", 100, 200)))
+	require.NoError(t, srv.Send(usageDone("resp_2", "conv_2", 300, 400)))
+
+	require.Eventually(t, func() bool {
+		return client.Usage().Total.Responses == 2
+	}, time.Second, 5*time.Millisecond)
+
+	usage := client.Usage()
+	require.Equal(t, 40, usage.Total.InputTextTokens)
+	require.Equal(t, 20, usage.Total.CachedTextTokens)
+	require.Equal(t, 400, usage.Total.InputAudioTokens)
+	require.Equal(t, 600, usage.Total.OutputAudioTokens)
+	require.Equal(t, 440, usage.Total.InputTokens())
+	// per response 10 uncached and 10 cached text tokens
+	require.InDelta(t, (2*(10+5)+4000+12000)/1e6, usage.Total.Cost, 1e-12)
+
+	require.Len(t, usage.Conversations, 2)
+	require.Equal(t, 1, usage.Conversations["conv_1"].Responses)
+	require.Equal(t, 200, usage.Conversations["conv_1"].OutputAudioTokens)
+}
+
+func TestBudget(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	srv := openairttest.NewServer()
+	defer srv.Close()
+
+	client := New(WithKey("test"), WithBaseURL(srv.URL), WithModel("test-model"), WithBudget(0.01), WithPrices(PriceTable{
+		"test-model": {OutputAudio: 100},
+	}))
+
+	exceeded := make(chan *BudgetExceededEvent, 1)
+	client.OnEvent(func(e any) {
+		if x, ok := e.(*BudgetExceededEvent); ok {
+			exceeded <- x
+		}
+	})
+	require.NoError(t, client.Open(ctx))
+
+	require.NoError(t, srv.Send(map[string]any{
+		"type":     "response.created",
+		"event_id": "evt_created",
+		"response": map[string]any{"id": "resp_2", "status": "in_progress"},
+	}))
+	// 200 audio tokens cost 0.02 USD
+	require.NoError(t, srv.Send(usageDone("resp_1", "conv_1", 0, 200)))
+
+	select {
+	case evt := <-exceeded:
+		require.InDelta(t, 0.02, evt.Cost, 1e-9)
+		require.Equal(t, 0.01, evt.Budget)
+	case <-ctx.Done():
+		t.Fatal("budget not exceeded")
+	}
+
+	evt, err := srv.Next(ctx, "response.cancel")
+	require.NoError(t, err)
+	var cancelEvt events.ResponseCancelEvent
+	require.NoError(t, evt.Decode(&cancelEvt))
+	require.Equal(t, "resp_2", cancelEvt.ResponseID)
+
+	require.ErrorIs(t, client.CreateResponse(), ErrBudgetExceeded)
+	require.ErrorIs(t, client.UserInput("hello", true), ErrBudgetExceeded)
+}
+
+func TestBudgetRequiresPrices(t *testing.T) {
+	client := New(WithKey("test"), WithModel("test-model"), WithBudget(1))
+	require.Error(t, client.Open(context.Background()))
+}
@@ -0,0 +1,144 @@
+package openairt
+
+import (
+	"bytes"
+	"github.com/codewandler/openairt-go/audio/vad"
+	"github.com/codewandler/openairt-go/events"
+	"log/slog"
+	"time"
+)
+
+// VADConfig configures the local voice activity detection, see WithVAD.
+type VADConfig struct {
+	// Config tunes the detector, its sample rate is set by the client.
+	vad.Config
+	// Gate sends audio to the API only while the user speaks, plus
+	// PrefixPadding before the start of speech. Without TurnDetection the
+	// Hangover of the detector should exceed the silence duration of the
+	// server VAD.
+	Gate bool
+	// PrefixPadding is the audio sent before the detected start of speech,
+	// 300ms by default.
+	PrefixPadding time.Duration
+	// BargeIn stops the response audio as soon as the user starts speaking,
+	// without waiting for input_audio_buffer.speech_started.
+	BargeIn bool
+	// TurnDetection disables the turn detection of the server. The client
+	// commits the input and requests a response when the user stops
+	// speaking.
+	TurnDetection bool
+}
+
+// LocalSpeechEvent is dispatched to OnEvent when the local VAD detects the
+// start or end of speech.
+type LocalSpeechEvent struct {
+	Started bool
+}
+
+// localVAD runs the detector on the input audio and gates it.
+type localVAD struct {
+	config    VADConfig
+	detector  *vad.Detector
+	decode    func([]byte) []byte
+	prefix    [][]byte
+	maxPrefix int
+}
+
+func newLocalVAD(config *clientConfig) *localVAD {
+	vc := *config.vad
+	if vc.PrefixPadding <= 0 {
+		vc.PrefixPadding = 300 * time.Millisecond
+	}
+
+	rate, sampleSize := config.audioRate(config.inFormat)
+	vc.SampleRate = rate
+	if config.inFormat == events.AudioFormatPCM16 {
+		// the audio sent to the API is converted to its sample rate
+		rate = apiSampleRate
+	}
+
+	return &localVAD{
+		config:    vc,
+		detector:  vad.New(vc.Config),
+		decode:    recorderDecoder(config.inFormat),
+		maxPrefix: int(float64(rate)*vc.PrefixPadding.Seconds()) * sampleSize,
+	}
+}
+
+// process analyzes a chunk of the input audio and returns the audio to send
+// for it: nothing while the user is silent, the held back prefix and data at
+// the start of speech.
+func (v *localVAD) process(chunk, data []byte) ([]byte, vad.Event) {
+	pcm := chunk
+	if v.decode != nil {
+		pcm = v.decode(chunk)
+	}
+	evt := v.detector.Write(pcm)
+
+	if !v.config.Gate {
+		return data, evt
+	}
+
+	if !v.detector.Speaking() && evt != vad.SpeechStopped {
+		v.hold(data)
+		return nil, evt
+	}
+
+	if len(v.prefix) == 0 {
+		return data, evt
+	}
+	out := bytes.Join(append(v.prefix, data), nil)
+	v.prefix = v.prefix[:0]
+	return out, evt
+}
+
+// hold keeps a copy of data as prefix, dropping the oldest audio beyond the
+// prefix padding.
+func (v *localVAD) hold(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	v.prefix = append(v.prefix, bytes.Clone(data))
+
+	size := 0
+	for _, p := range v.prefix {
+		size += len(p)
+	}
+	for len(v.prefix) > 1 && size-len(v.prefix[0]) >= v.maxPrefix {
+		size -= len(v.prefix[0])
+		v.prefix = v.prefix[1:]
+	}
+}
+
+// handleLocalSpeech acts on a change of the local speech state.
+func (c *Client) handleLocalSpeech(evt vad.Event) {
+	switch evt {
+	case vad.SpeechStarted:
+		if c.vad.config.BargeIn {
+			c.interruptOutput("")
+			c.cancelActiveResponses()
+		}
+	case vad.SpeechStopped:
+		c.telemetry.speechStopped()
+		c.turns.speechStopped("")
+		if c.vad.config.TurnDetection {
+			if err := c.CommitInput(); err != nil {
+				c.log().Error("failed to commit input audio", slog.Any("err", err))
+				return
+			}
+			// the rate limiter may hold the response back, the input
+			// audio keeps flowing meanwhile
+			go func() {
+				if err := c.CreateResponse(); err != nil {
+					c.log().Error("failed to create response", slog.Any("err", err))
+				}
+			}()
+		}
+	default:
+		return
+	}
+
+	if c.onEvent != nil {
+		c.onEvent(&LocalSpeechEvent{Started: evt == vad.SpeechStarted})
+	}
+}
@@ -0,0 +1,171 @@
+package openairt
+
+import (
+	"context"
+	"encoding/base64"
+	"github.com/codewandler/openairt-go/audio/vad"
+	"github.com/codewandler/openairt-go/events"
+	"github.com/codewandler/openairt-go/openairttest"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestVADTurnDetection(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	srv := openairttest.NewServer()
+	defer srv.Close()
+
+	client := New(WithKey("test"), WithBaseURL(srv.URL), WithSampleRate(16_000), WithVAD(VADConfig{
+		Config:        vad.Config{Hangover: 200 * time.Millisecond},
+		Gate:          true,
+		TurnDetection: true,
+	}))
+
+	speech := make(chan bool, 2)
+	client.OnEvent(func(e any) {
+		if x, ok := e.(*LocalSpeechEvent); ok {
+			speech <- x.Started
+		}
+	})
+	require.NoError(t, client.Open(ctx))
+
+	// server VAD is disabled
+	evt, err := srv.Next(ctx, "session.update")
+	require.NoError(t, err)
+	require.Contains(t, string(evt.Data), `"turn_detection":null`)
+
+	go func() {
+		audio := client.Audio()
+		_, _ = audio.Write(make([]byte, 16_000))          // 500ms silence
+		_, _ = audio.Write(sine(440, 16_000, 8_000, 0.3)) // 500ms speech
+		_, _ = audio.Write(make([]byte, 32_000))          // 1s silence
+	}()
+
+	// only speech, the prefix and the hangover are sent, then committed
+	var sent int
+	for {
+		evt, err := srv.NextOf(ctx, "input_audio_buffer.append", "input_audio_buffer.commit")
+		require.NoError(t, err)
+		if evt.Type == "input_audio_buffer.commit" {
+			break
+		}
+
+		var msg struct {
+			Audio string `json:"audio"`
+		}
+		require.NoError(t, evt.Decode(&msg))
+		data, err := base64.StdEncoding.DecodeString(msg.Audio)
+		require.NoError(t, err)
+		sent += len(data)
+	}
+
+	// about 1s at 24 kHz: 300ms prefix, 500ms speech, 200ms hangover
+	require.InDelta(t, 48_000, sent, 4_800)
+
+	_, err = srv.Next(ctx, "response.create")
+	require.NoError(t, err)
+
+	require.True(t, <-speech)
+	require.False(t, <-speech)
+}
+
+func TestVADTurnDetectionRateLimited(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	srv := openairttest.NewServer()
+	defer srv.Close()
+
+	limiter := NewRateLimiter(RateLimiterConfig{})
+	limiter.Update([]events.RateLimit{{Name: "requests", Limit: 10, Remaining: 0, ResetSeconds: 60}})
+	client := New(WithKey("test"), WithBaseURL(srv.URL), WithSampleRate(16_000), WithRateLimiter(limiter), WithVAD(VADConfig{
+		Config:        vad.Config{Hangover: 200 * time.Millisecond},
+		TurnDetection: true,
+	}))
+	require.NoError(t, client.Open(ctx))
+
+	go func() {
+		audio := client.Audio()
+		_, _ = audio.Write(sine(440, 16_000, 8_000, 0.3)) // 500ms speech
+		_, _ = audio.Write(make([]byte, 32_000))          // 1s silence
+	}()
+
+	_, err := srv.Next(ctx, "input_audio_buffer.commit")
+	require.NoError(t, err)
+
+	// the held back response does not stop the input audio
+	_, err = srv.Next(ctx, "input_audio_buffer.append")
+	require.NoError(t, err)
+}
+
+func TestVADBargeIn(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, srv := openTestClient(t, WithSampleRate(16_000), WithVAD(VADConfig{BargeIn: true}))
+
+	require.NoError(t, srv.Send(map[string]any{
+		"type":     "response.created",
+		"event_id": "evt_1",
+		"response": map[string]any{"id": "resp_1", "status": "in_progress"},
+	}))
+	require.Eventually(t, func() bool {
+		client.activeMu.Lock()
+		defer client.activeMu.Unlock()
+		return client.active["resp_1"]
+	}, time.Second, time.Millisecond)
+
+	// the agent audio streams in before and after the user barges in
+	streamed := make(chan struct{})
+	go func() {
+		defer close(streamed)
+		delta := base64.StdEncoding.EncodeToString(sine(220, 24_000, 480, 0.3))
+		for range 200 {
+			_ = srv.Send(map[string]any{
+				"type":        "response.audio.delta",
+				"event_id":    "evt_delta",
+				"response_id": "resp_1",
+				"item_id":     "item_1",
+				"delta":       delta,
+			})
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	require.Eventually(t, func() bool {
+		return !client.audioToUser.IsEmpty()
+	}, time.Second, time.Millisecond)
+
+	_, err := client.Audio().Write(sine(440, 16_000, 3_200, 0.3))
+	require.NoError(t, err)
+
+	evt, err := srv.Next(ctx, "response.cancel")
+	require.NoError(t, err)
+	require.Contains(t, string(evt.Data), "resp_1")
+	<-streamed
+}
+
+func TestVADBargeInDuringAudio(t *testing.T) {
+	client, _ := openTestClient(t, WithSampleRate(16_000), WithVAD(VADConfig{BargeIn: true}))
+	delta := &events.ResponseAudioDeltaEvent{
+		ResponseId: "resp_1",
+		ItemID:     "item_1",
+		Delta:      base64.StdEncoding.EncodeToString(sine(220, 24_000, 480, 0.3)),
+	}
+
+	// the input pump barges in while the read loop converts agent audio
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 100 {
+			client.handleLocalSpeech(vad.SpeechStarted)
+		}
+	}()
+	for range 100 {
+		client.handleAudioDelta(delta)
+	}
+	<-done
+}
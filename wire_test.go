@@ -0,0 +1,110 @@
+package openairt
+
+import (
+	"bytes"
+	"context"
+	"github.com/codewandler/openairt-go/openairttest"
+	"github.com/codewandler/openairt-go/wirelog"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+// recordToolCall runs a tool call flow against the mock server and returns
+// its recording.
+func recordToolCall(t *testing.T, ctx context.Context) []wirelog.Frame {
+	t.Helper()
+
+	var buf bytes.Buffer
+	client, srv := openTestClient(t, WithWireRecorder(wirelog.NewRecorder(&buf, wirelog.Options{ElideAudio: true})))
+	client.OnToolCall(func(name string, args map[string]any) (any, error) {
+		return "sunny", nil
+	})
+
+	require.NoError(t, client.UserInput("weather?", true))
+	_, err := srv.Next(ctx, "response.create")
+	require.NoError(t, err)
+
+	sendAudioDelta(t, srv, "item_1", make([]byte, 160))
+	require.NoError(t, srv.Send(functionCallDone("get_weather", "call_1", `{"city":"Berlin"}`)))
+	_, err = srv.Next(ctx, "response.create")
+	require.NoError(t, err)
+
+	require.NoError(t, client.Close(ctx))
+
+	// frames are recorded when they are written, after Next returned
+	var frames []wirelog.Frame
+	require.Eventually(t, func() bool {
+		frames, err = wirelog.Read(bytes.NewReader(buf.Bytes()))
+		require.NoError(t, err)
+		return len(frames) > 0 && frames[len(frames)-1].Type == "response.create"
+	}, time.Second, 5*time.Millisecond)
+
+	return frames
+}
+
+func TestWireRecorder(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	frames := recordToolCall(t, ctx)
+
+	var types []string
+	for _, f := range frames {
+		types = append(types, string(f.Dir)+" "+f.Type)
+		if f.Type == "response.audio.delta" {
+			require.True(t, f.Elided)
+		}
+	}
+	require.Subset(t, types, []string{
+		"in session.created",
+		"out session.update",
+		"out conversation.item.create",
+		"out response.create",
+		"in response.audio.delta",
+		"in response.done",
+	})
+}
+
+func TestWireReplay(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	frames := recordToolCall(t, ctx)
+
+	// the recording drives a new client through the same flow
+	srv := openairttest.NewServer()
+	defer srv.Close()
+
+	client := New(WithKey("test"), WithBaseURL(srv.URL))
+	calls := make(chan string, 1)
+	client.OnToolCall(func(name string, args map[string]any) (any, error) {
+		calls <- name
+		return "sunny", nil
+	})
+	require.NoError(t, client.Open(ctx))
+	defer func() { _ = client.Close(ctx) }()
+
+	replayed := make(chan error, 1)
+	go func() {
+		replayed <- srv.Replay(ctx, frames, openairttest.ReplayOptions{Timing: true})
+	}()
+
+	// the recorded user input is what the replay waits for first
+	require.NoError(t, client.UserInput("weather?", true))
+	require.NoError(t, <-replayed)
+	require.Equal(t, "get_weather", <-calls)
+}
+
+func TestWireReplayMismatch(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	_, srv := openTestClient(t)
+
+	// the client never sends the recorded commit
+	err := srv.Replay(ctx, []wirelog.Frame{
+		{Dir: wirelog.Out, Type: "input_audio_buffer.commit"},
+	}, openairttest.ReplayOptions{})
+	require.ErrorContains(t, err, "input_audio_buffer.commit")
+}
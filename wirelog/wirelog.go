@@ -0,0 +1,113 @@
+// Package wirelog records the websocket frames of a session as JSON lines
+// and reads them back, e.g. to replay a session with openairttest.
+package wirelog
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Direction of a frame as seen by the client.
+type Direction string
+
+const (
+	In  Direction = "in"
+	Out Direction = "out"
+)
+
+// Frame is a recorded websocket text frame.
+type Frame struct {
+	Time time.Time       `json:"t"`
+	Dir  Direction       `json:"dir"`
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+	// Elided is set if the audio of the frame was removed.
+	Elided bool `json:"elided,omitempty"`
+}
+
+// audioFields are the fields holding base64 audio by event type.
+var audioFields = map[string]string{
+	"input_audio_buffer.append": "audio",
+	"response.audio.delta":      "delta",
+}
+
+// Options configure a Recorder.
+type Options struct {
+	// ElideAudio replaces the audio of input_audio_buffer.append and
+	// response.audio.delta with an empty string.
+	ElideAudio bool
+}
+
+// Recorder writes frames to a JSONL stream. It is safe for concurrent use.
+type Recorder struct {
+	opts Options
+
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+// NewRecorder creates a Recorder writing to w.
+func NewRecorder(w io.Writer, opts Options) *Recorder {
+	return &Recorder{opts: opts, enc: json.NewEncoder(w)}
+}
+
+// Record writes a frame sent or received now. Frames that are not JSON
+// objects are recorded with an empty type.
+func (r *Recorder) Record(dir Direction, data []byte) {
+	f := Frame{Time: time.Now(), Dir: dir, Data: data}
+
+	var head map[string]json.RawMessage
+	if err := json.Unmarshal(data, &head); err == nil {
+		_ = json.Unmarshal(head["type"], &f.Type)
+
+		if field, ok := audioFields[f.Type]; ok && r.opts.ElideAudio {
+			head[field] = json.RawMessage(`""`)
+			if elided, err := json.Marshal(head); err == nil {
+				f.Data, f.Elided = elided, true
+			}
+		}
+	} else {
+		// keep the line valid JSON
+		f.Data, _ = json.Marshal(string(data))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err == nil {
+		r.err = r.enc.Encode(f)
+	}
+}
+
+// Err returns the first write error.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.err
+}
+
+// Read reads all frames of a recording.
+func Read(rd io.Reader) ([]Frame, error) {
+	var frames []Frame
+
+	sc := bufio.NewScanner(rd)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var f Frame
+		if err := json.Unmarshal(sc.Bytes(), &f); err != nil {
+			return nil, fmt.Errorf("invalid frame in line %d: %w", line, err)
+		}
+		frames = append(frames, f)
+	}
+
+	return frames, sc.Err()
+}
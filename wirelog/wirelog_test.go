@@ -0,0 +1,58 @@
+package wirelog
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewRecorder(&buf, Options{ElideAudio: true})
+
+	r.Record(Out, []byte(`{"type":"input_audio_buffer.append","audio":"AAAA"}`))
+	r.Record(In, []byte(`{"type":"response.audio.delta","item_id":"item_1","delta":"AAAA"}`))
+	r.Record(In, []byte(`{"type":"response.done","response":{"id":"resp_1"}}`))
+	r.Record(In, []byte(`not json`))
+	require.NoError(t, r.Err())
+
+	frames, err := Read(&buf)
+	require.NoError(t, err)
+	require.Len(t, frames, 4)
+
+	require.Equal(t, Out, frames[0].Dir)
+	require.Equal(t, "input_audio_buffer.append", frames[0].Type)
+	require.True(t, frames[0].Elided)
+	require.JSONEq(t, `{"type":"input_audio_buffer.append","audio":""}`, string(frames[0].Data))
+
+	require.Equal(t, In, frames[1].Dir)
+	require.True(t, frames[1].Elided)
+	require.JSONEq(t, `{"type":"response.audio.delta","item_id":"item_1","delta":""}`, string(frames[1].Data))
+
+	require.False(t, frames[2].Elided)
+	require.JSONEq(t, `{"type":"response.done","response":{"id":"resp_1"}}`, string(frames[2].Data))
+
+	require.Empty(t, frames[3].Type)
+	var s string
+	require.NoError(t, json.Unmarshal(frames[3].Data, &s))
+	require.Equal(t, "not json", s)
+
+	require.False(t, frames[0].Time.After(frames[3].Time))
+}
+
+func TestRecorderKeepsAudio(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewRecorder(&buf, Options{})
+	r.Record(In, []byte(`{"type":"response.audio.delta","delta":"AAAA"}`))
+
+	frames, err := Read(&buf)
+	require.NoError(t, err)
+	require.False(t, frames[0].Elided)
+	require.JSONEq(t, `{"type":"response.audio.delta","delta":"AAAA"}`, string(frames[0].Data))
+}
+
+func TestReadInvalid(t *testing.T) {
+	_, err := Read(bytes.NewBufferString("{\"dir\":\"in\"}\n{"))
+	require.ErrorContains(t, err, "line 2")
+}